@@ -0,0 +1,39 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// NewHandler создает HTTP обработчик административного сервера.
+// Если levels не nil, добавляются методы чтения и изменения уровней логов;
+// изменение требует заголовка Authorization: Bearer <token>.
+func NewHandler(metrics http.Handler, levels *Levels, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics)
+
+	if levels != nil {
+		mux.HandleFunc("GET /log/levels", levels.list)
+		mux.HandleFunc("PUT /log/levels/{handler}", requireToken(token, levels.set))
+	}
+	return mux
+}
+
+// requireToken пропускает запрос только с bearer токеном token.
+// Если токен не задан, методы, изменяющие состояние сервиса, недоступны.
+func requireToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			writeError(w, http.StatusForbidden, "ADMIN_TOKEN is not configured")
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "invalid or missing admin token")
+			return
+		}
+		next(w, r)
+	}
+}
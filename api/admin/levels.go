@@ -0,0 +1,163 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LevelController управляет минимальными уровнями обработчиков логгера по имени (например, *logger.MultiHandler)
+type LevelController interface {
+	Levels() map[string]slog.Level
+	SetHandlerLevel(name string, level slog.Level) error
+}
+
+// levelChange — ответ на изменение уровня обработчика
+type levelChange struct {
+	Handler  string     `json:"handler"`
+	Level    string     `json:"level"`
+	Previous string     `json:"previous"`
+	RevertAt *time.Time `json:"revert_at,omitempty"` // Время автоматического возврата прежнего уровня
+}
+
+// Levels читает и изменяет уровни логов. Изменение через административный сервер действует,
+// пока не истечет revert_after или не придет новое изменение того же обработчика; уровни
+// из конфигурации для такого обработчика только запоминаются и применяются после возврата.
+type Levels struct {
+	levels LevelController
+	logger *slog.Logger
+
+	mu         sync.Mutex
+	configured map[string]slog.Level  // Последние уровни из конфигурации
+	overrides  map[string]*time.Timer // Действующие изменения; nil, если возврат не запланирован
+}
+
+// NewLevels создает Levels поверх обработчиков логгера
+func NewLevels(levels LevelController, logger *slog.Logger) *Levels {
+	return &Levels{
+		levels:     levels,
+		logger:     logger,
+		configured: make(map[string]slog.Level),
+		overrides:  make(map[string]*time.Timer),
+	}
+}
+
+// Configure применяет уровни из конфигурации ко всем обработчикам, кроме измененных
+// через административный сервер. Для них уровень применяется при возврате.
+func (h *Levels) Configure(levels map[string]slog.Level) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for name, level := range levels {
+		h.configured[name] = level
+		if _, overridden := h.overrides[name]; overridden {
+			continue
+		}
+		if err := h.levels.SetHandlerLevel(name, level); err != nil {
+			h.logger.Warn("failed to set log level", slog.String("handler", name), slog.Any("error", err))
+		}
+	}
+}
+
+// list возвращает уровни всех обработчиков: GET /log/levels
+func (h *Levels) list(w http.ResponseWriter, _ *http.Request) {
+	levels := make(map[string]string)
+	for name, level := range h.levels.Levels() {
+		levels[name] = formatLevel(level)
+	}
+	writeJSON(w, http.StatusOK, levels)
+}
+
+// set изменяет уровень обработчика: PUT /log/levels/{handler}?level=debug&revert_after=10m
+func (h *Levels) set(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("handler")
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(r.URL.Query().Get("level"))); err != nil {
+		writeError(w, http.StatusBadRequest, "level must be debug, info, warn or error")
+		return
+	}
+
+	var revertAfter time.Duration
+	if value := r.URL.Query().Get("revert_after"); value != "" {
+		var err error
+		if revertAfter, err = time.ParseDuration(value); err != nil || revertAfter <= 0 {
+			writeError(w, http.StatusBadRequest, "revert_after must be a positive duration such as 10m")
+			return
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	previous, ok := h.levels.Levels()[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown log handler %q", name))
+		return
+	}
+	if err := h.levels.SetHandlerLevel(name, level); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if timer := h.overrides[name]; timer != nil {
+		timer.Stop()
+	}
+	// Без revert_after изменение действует до следующего изменения через административный сервер
+	h.overrides[name] = nil
+
+	change := levelChange{Handler: name, Level: formatLevel(level), Previous: formatLevel(previous)}
+	if revertAfter > 0 {
+		revertAt := time.Now().Add(revertAfter)
+		change.RevertAt = &revertAt
+		var timer *time.Timer
+		// Таймер читается в revert под блокировкой, так как присваивается после запуска
+		timer = time.AfterFunc(revertAfter, func() { h.revert(name, previous, &timer) })
+		h.overrides[name] = timer
+	}
+
+	h.logger.Info(fmt.Sprintf("log level of %s changed from %s to %s", name, change.Previous, change.Level),
+		slog.Duration("revert_after", revertAfter))
+	writeJSON(w, http.StatusOK, change)
+}
+
+// revert возвращает уровень из конфигурации или, если он не задан, прежний уровень,
+// если возврат не был отменен новым изменением
+func (h *Levels) revert(name string, level slog.Level, timer **time.Timer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.overrides[name] != *timer {
+		return
+	}
+	delete(h.overrides, name)
+	if configured, ok := h.configured[name]; ok {
+		level = configured
+	}
+
+	if err := h.levels.SetHandlerLevel(name, level); err != nil {
+		h.logger.Error(fmt.Sprintf("failed to revert log level of %s", name), slog.Any("error", err))
+		return
+	}
+	h.logger.Info(fmt.Sprintf("log level of %s reverted to %s", name, formatLevel(level)))
+}
+
+// formatLevel возвращает уровень в том же виде, в котором он задается в конфигурации
+func formatLevel(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
+
+// writeJSON записывает ответ в формате JSON
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError записывает ошибку в формате {"error": "..."}
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
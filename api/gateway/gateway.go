@@ -0,0 +1,241 @@
+package gateway
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/watchlist-kata/protos/watchlist"
+	"github.com/watchlist-kata/watchlist/internal/interceptors"
+	"github.com/watchlist-kata/watchlist/internal/ratelimit"
+)
+
+// openAPISpec — описание REST API в формате OpenAPI 3
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// maxRequestBodySize — максимальный размер тела запроса в байтах; тела запросов шлюза — небольшие JSON объекты
+const maxRequestBodySize = 64 << 10
+
+// forwardedHeaders — HTTP заголовки, которые передаются в gRPC метаданные запроса
+var forwardedHeaders = []string{"Authorization", "Accept-Language", "X-Request-Id"}
+
+var (
+	marshaler   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	unmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+// Gateway транслирует HTTP/JSON запросы в вызовы WatchlistService
+type Gateway struct {
+	client watchlist.WatchlistServiceClient
+	logger *slog.Logger
+}
+
+// NewHandler создает HTTP обработчик REST шлюза поверх gRPC клиента WatchlistService
+func NewHandler(client watchlist.WatchlistServiceClient, logger *slog.Logger) http.Handler {
+	g := &Gateway{client: client, logger: logger}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/users/{user_id}/watchlist", g.addToWatchlist)
+	mux.HandleFunc("DELETE /v1/users/{user_id}/watchlist/{media_id}", g.removeFromWatchlist)
+	mux.HandleFunc("GET /v1/users/{user_id}/watchlist", g.getWatchlist)
+	mux.HandleFunc("GET /v1/users/{user_id}/watchlist/{media_id}", g.checkInWatchlist)
+	mux.HandleFunc("GET /openapi.yaml", serveOpenAPI)
+	return mux
+}
+
+// addToWatchlist обрабатывает POST /v1/users/{user_id}/watchlist
+func (g *Gateway) addToWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := g.pathInt(w, r, "user_id")
+	if !ok {
+		return
+	}
+
+	req := &watchlist.AddToWatchlistRequest{}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			g.writeError(w, r, status.Errorf(codes.InvalidArgument, "request body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		g.writeError(w, r, status.Error(codes.InvalidArgument, "failed to read request body"))
+		return
+	}
+	if len(body) > 0 {
+		if err := unmarshaler.Unmarshal(body, req); err != nil {
+			g.writeError(w, r, status.Errorf(codes.InvalidArgument, "invalid request body: %v", err))
+			return
+		}
+	}
+	req.UserId = userID
+
+	var header, trailer metadata.MD
+	resp, err := g.client.AddToWatchlist(outgoingContext(r), req, grpc.Header(&header), grpc.Trailer(&trailer))
+	g.writeResponse(w, r, resp, header, trailer, err)
+}
+
+// removeFromWatchlist обрабатывает DELETE /v1/users/{user_id}/watchlist/{media_id}
+func (g *Gateway) removeFromWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := g.pathInt(w, r, "user_id")
+	if !ok {
+		return
+	}
+	mediaID, ok := g.pathInt(w, r, "media_id")
+	if !ok {
+		return
+	}
+
+	var header, trailer metadata.MD
+	resp, err := g.client.RemoveFromWatchlist(outgoingContext(r), &watchlist.RemoveFromWatchlistRequest{MediaId: mediaID, UserId: userID}, grpc.Header(&header), grpc.Trailer(&trailer))
+	g.writeResponse(w, r, resp, header, trailer, err)
+}
+
+// getWatchlist обрабатывает GET /v1/users/{user_id}/watchlist
+func (g *Gateway) getWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := g.pathInt(w, r, "user_id")
+	if !ok {
+		return
+	}
+
+	var header, trailer metadata.MD
+	resp, err := g.client.GetWatchlist(outgoingContext(r), &watchlist.GetWatchlistRequest{UserId: userID}, grpc.Header(&header), grpc.Trailer(&trailer))
+	g.writeResponse(w, r, resp, header, trailer, err)
+}
+
+// checkInWatchlist обрабатывает GET /v1/users/{user_id}/watchlist/{media_id}
+func (g *Gateway) checkInWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := g.pathInt(w, r, "user_id")
+	if !ok {
+		return
+	}
+	mediaID, ok := g.pathInt(w, r, "media_id")
+	if !ok {
+		return
+	}
+
+	var header, trailer metadata.MD
+	resp, err := g.client.CheckInWatchlist(outgoingContext(r), &watchlist.CheckInWatchlistRequest{MediaId: mediaID, UserId: userID}, grpc.Header(&header), grpc.Trailer(&trailer))
+	g.writeResponse(w, r, resp, header, trailer, err)
+}
+
+// serveOpenAPI отдает описание REST API
+func serveOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPISpec)
+}
+
+// pathInt читает целочисленный параметр пути и при ошибке отвечает 400
+func (g *Gateway) pathInt(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	value, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil {
+		g.writeError(w, r, status.Errorf(codes.InvalidArgument, "invalid %s: %q", name, r.PathValue(name)))
+		return 0, false
+	}
+	return value, true
+}
+
+// outgoingContext переносит поддерживаемые HTTP заголовки в gRPC метаданные.
+// Адрес клиента берется из соединения, а не из заголовка X-Forwarded-For, который клиент может подделать.
+func outgoingContext(r *http.Request) context.Context {
+	md := metadata.MD{}
+	for _, header := range forwardedHeaders {
+		if value := r.Header.Get(header); value != "" {
+			md.Set(header, value)
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		md.Set(ratelimit.ForwardedForKey, host)
+	} else if r.RemoteAddr != "" {
+		md.Set(ratelimit.ForwardedForKey, r.RemoteAddr)
+	}
+	return metadata.NewOutgoingContext(r.Context(), md)
+}
+
+// writeResponse пишет ответ gRPC метода в формате JSON или ошибку
+func (g *Gateway) writeResponse(w http.ResponseWriter, r *http.Request, resp proto.Message, header, trailer metadata.MD, err error) {
+	// Идентификатор запроса возвращается клиенту, чтобы по нему можно было найти записи в логах
+	if requestID := header.Get(interceptors.RequestIDKey); len(requestID) > 0 {
+		w.Header().Set("X-Request-Id", requestID[0])
+	}
+
+	if err != nil {
+		// Рекомендуемая задержка перед повтором передается клиенту в заголовке Retry-After
+		if retryAfter := trailer.Get(ratelimit.RetryAfterKey); len(retryAfter) > 0 {
+			w.Header().Set("Retry-After", retryAfter[0])
+		}
+		g.writeError(w, r, err)
+		return
+	}
+
+	payload, err := marshaler.Marshal(resp)
+	if err != nil {
+		g.logger.ErrorContext(r.Context(), "failed to marshal gateway response", slog.Any("error", err))
+		g.writeError(w, r, status.Error(codes.Internal, "failed to marshal response"))
+		return
+	}
+	writeJSON(w, http.StatusOK, payload)
+}
+
+// writeError пишет gRPC статус в теле ответа (в формате google.rpc.Status) с соответствующим HTTP статусом
+func (g *Gateway) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	st := status.Convert(err)
+
+	payload, marshalErr := marshaler.Marshal(st.Proto())
+	if marshalErr != nil {
+		g.logger.WarnContext(r.Context(), "failed to marshal gateway error details", slog.Any("error", marshalErr))
+		payload = []byte(fmt.Sprintf(`{"code":%d,"message":%q}`, st.Code(), st.Message()))
+	}
+	writeJSON(w, HTTPStatusFromCode(st.Code()), payload)
+}
+
+// writeJSON пишет JSON ответ с указанным HTTP статусом
+func writeJSON(w http.ResponseWriter, code int, payload []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(payload)
+}
+
+// HTTPStatusFromCode сопоставляет код gRPC статусу HTTP
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // Client Closed Request
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
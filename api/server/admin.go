@@ -0,0 +1,41 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Тайм-ауты административного HTTP сервера: медленные клиенты не должны удерживать соединения бесконечно
+const (
+	adminReadHeaderTimeout = 5 * time.Second
+	adminReadTimeout       = 10 * time.Second
+	adminIdleTimeout       = 2 * time.Minute
+)
+
+// startAdminServer запускает административный HTTP сервер (метрики) на addr.
+// Ошибки работы сервера отправляются в serveErr.
+func startAdminServer(addr string, handler http.Handler, logger *slog.Logger, serveErr chan<- error) (*http.Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: adminReadHeaderTimeout,
+		ReadTimeout:       adminReadTimeout,
+		IdleTimeout:       adminIdleTimeout,
+	}
+	go func() {
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("admin HTTP server: %w", err)
+		}
+	}()
+
+	logger.Info("starting admin server", slog.String("port", addr))
+	return srv, nil
+}
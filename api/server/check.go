@@ -0,0 +1,52 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/watchlist-kata/watchlist/internal/auth"
+	"github.com/watchlist-kata/watchlist/internal/config"
+	"github.com/watchlist-kata/watchlist/internal/i18n"
+	"github.com/watchlist-kata/watchlist/internal/tlsconfig"
+	"github.com/watchlist-kata/watchlist/pkg/utils"
+)
+
+// CheckConfig проверяет параметры, которые RunServer отклонил бы только при запуске:
+// язык по умолчанию, строку подключения к базе данных с файлами секретов, цепочку перехватчиков,
+// ключи аутентификации и сертификаты TLS.
+// Возвращает все найденные ошибки, объединенные errors.Join.
+func CheckConfig(cfg *config.Config, logger *slog.Logger) error {
+	var errs []error
+
+	if _, err := i18n.NewResolver(cfg.DefaultLocale); err != nil {
+		errs = append(errs, fmt.Errorf("invalid default locale: %w", err))
+	}
+
+	if _, err := utils.ParseDatabaseDSN(cfg); err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, err := buildInterceptorChain(cfg.GRPCInterceptors, nil, nil); err != nil {
+		errs = append(errs, fmt.Errorf("invalid interceptor chain: %w", err))
+	}
+
+	if cfg.AuthEnabled {
+		if _, err := auth.NewKeySet(cfg.AuthJWKSFile, cfg.AuthStaticKeyFile, cfg.AuthKeysRefresh); err != nil {
+			errs = append(errs, fmt.Errorf("failed to load authentication keys: %w", err))
+		}
+	}
+
+	if cfg.TLSCertFile != "" {
+		if _, err := tlsconfig.NewReloader(tlsconfig.Options{
+			CertFile:     cfg.TLSCertFile,
+			KeyFile:      cfg.TLSKeyFile,
+			ClientCAFile: cfg.TLSClientCAFile,
+			ClientAuth:   cfg.TLSClientAuth,
+		}, logger); err != nil {
+			errs = append(errs, fmt.Errorf("failed to load TLS configuration: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
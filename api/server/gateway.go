@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/watchlist-kata/protos/watchlist"
+	"github.com/watchlist-kata/watchlist/api/gateway"
+	"github.com/watchlist-kata/watchlist/internal/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Тайм-ауты HTTP сервера шлюза: медленные клиенты не должны удерживать соединения бесконечно
+const (
+	gatewayReadHeaderTimeout = 5 * time.Second
+	gatewayReadTimeout       = 30 * time.Second
+	gatewayIdleTimeout       = 2 * time.Minute
+)
+
+// pipeListener — in-memory net.Listener на основе net.Pipe, через который REST шлюз
+// обращается к своему gRPC серверу
+type pipeListener struct {
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newPipeListener создает pipeListener
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+// Accept возвращает серверную сторону очередного соединения
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close закрывает listener; новые соединения после этого не принимаются
+func (l *pipeListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return nil
+}
+
+// Addr возвращает адрес listener
+func (l *pipeListener) Addr() net.Addr {
+	return gatewayAddr{}
+}
+
+// DialContext создает соединение и передает его серверную сторону в Accept
+func (l *pipeListener) DialContext(ctx context.Context) (net.Conn, error) {
+	server, client := net.Pipe()
+	select {
+	case l.conns <- gatewayConn{server}:
+		return gatewayConn{client}, nil
+	case <-l.done:
+		server.Close()
+		client.Close()
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		server.Close()
+		client.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// gatewayConn — соединение шлюза, адреса которого позволяют ограничителю запросов отличить шлюз от внешних клиентов
+type gatewayConn struct {
+	net.Conn
+}
+
+// LocalAddr возвращает адрес шлюза
+func (gatewayConn) LocalAddr() net.Addr { return gatewayAddr{} }
+
+// RemoteAddr возвращает адрес шлюза
+func (gatewayConn) RemoteAddr() net.Addr { return gatewayAddr{} }
+
+// gatewayAddr — адрес in-memory соединения шлюза
+type gatewayAddr struct{}
+
+// Network возвращает имя сети, по которому ограничитель запросов доверяет адресу клиента из метаданных
+func (gatewayAddr) Network() string { return ratelimit.GatewayNetwork }
+
+// String возвращает адрес в текстовом виде
+func (gatewayAddr) String() string { return ratelimit.GatewayNetwork }
+
+// restGateway — HTTP сервер REST шлюза и внутренний gRPC сервер, к которому он обращается
+type restGateway struct {
+	httpServer *http.Server
+	grpcServer *grpc.Server
+	conn       *grpc.ClientConn
+	logger     *slog.Logger
+}
+
+// startGateway запускает REST шлюз на addr. Шлюз вызывает WatchlistService через отдельный
+// gRPC сервер на in-memory соединении, поэтому запросы проходят те же перехватчики, что и gRPC запросы.
+// Ошибки работы серверов отправляются в serveErr.
+func startGateway(addr string, svc watchlist.WatchlistServiceServer, serverOpts []grpc.ServerOption, logger *slog.Logger, serveErr chan<- error) (*restGateway, error) {
+	lis := newPipeListener()
+
+	grpcServer := grpc.NewServer(serverOpts...)
+	watchlist.RegisterWatchlistServiceServer(grpcServer, svc)
+
+	conn, err := grpc.NewClient("passthrough:///gateway",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gateway client: %w", err)
+	}
+
+	httpLis, err := net.Listen("tcp", addr)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	g := &restGateway{
+		httpServer: &http.Server{
+			Handler:           gateway.NewHandler(watchlist.NewWatchlistServiceClient(conn), logger),
+			ReadHeaderTimeout: gatewayReadHeaderTimeout,
+			ReadTimeout:       gatewayReadTimeout,
+			IdleTimeout:       gatewayIdleTimeout,
+		},
+		grpcServer: grpcServer,
+		conn:       conn,
+		logger:     logger,
+	}
+
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			serveErr <- fmt.Errorf("gateway gRPC server: %w", err)
+		}
+	}()
+	go func() {
+		if err := g.httpServer.Serve(httpLis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("gateway HTTP server: %w", err)
+		}
+	}()
+
+	logger.Info("starting REST gateway", slog.String("port", addr))
+	return g, nil
+}
+
+// Shutdown дожидается завершения HTTP запросов до отмены ctx, затем останавливает внутренний gRPC сервер
+func (g *restGateway) Shutdown(ctx context.Context) {
+	if err := g.httpServer.Shutdown(ctx); err != nil {
+		g.logger.Warn("REST gateway shutdown timed out, closing remaining connections", slog.Any("error", err))
+		g.httpServer.Close()
+	}
+	g.conn.Close()
+	g.grpcServer.Stop()
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+
+	"google.golang.org/grpc"
+)
+
+// Имена перехватчиков для параметра GRPC_INTERCEPTORS
+const (
+	interceptorRecovery  = "recovery"
+	interceptorRequestID = "request_id"
+	interceptorLocale    = "locale"
+	interceptorMetrics   = "metrics"
+	interceptorAccessLog = "access_log"
+	interceptorTLSClient = "tls_client"
+	interceptorAuth      = "auth"
+	interceptorRateLimit = "rate_limit"
+)
+
+// knownInterceptors — все поддерживаемые имена перехватчиков
+var knownInterceptors = []string{
+	interceptorRecovery, interceptorRequestID, interceptorLocale, interceptorMetrics, interceptorAccessLog,
+	interceptorTLSClient, interceptorAuth, interceptorRateLimit,
+}
+
+// buildInterceptorChain возвращает перехватчики в порядке names. Имена из exclude и перехватчики,
+// отсутствующие в available (компонент выключен в конфигурации), пропускаются.
+func buildInterceptorChain(names []string, available map[string]grpc.UnaryServerInterceptor, exclude []string) ([]grpc.UnaryServerInterceptor, error) {
+	seen := make(map[string]bool, len(names))
+	chain := make([]grpc.UnaryServerInterceptor, 0, len(names))
+
+	for _, name := range names {
+		if !slices.Contains(knownInterceptors, name) {
+			return nil, fmt.Errorf("unknown interceptor %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate interceptor %q", name)
+		}
+		seen[name] = true
+
+		if slices.Contains(exclude, name) {
+			continue
+		}
+		if interceptor, ok := available[name]; ok {
+			chain = append(chain, interceptor)
+		}
+	}
+	return chain, nil
+}
@@ -1,27 +1,84 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net"
+	"net/http"
+	"sync/atomic"
 
 	"github.com/watchlist-kata/protos/watchlist"
+	"github.com/watchlist-kata/watchlist/api/admin"
+	"github.com/watchlist-kata/watchlist/internal/auth"
 	"github.com/watchlist-kata/watchlist/internal/config"
+	"github.com/watchlist-kata/watchlist/internal/healthcheck"
+	"github.com/watchlist-kata/watchlist/internal/i18n"
+	"github.com/watchlist-kata/watchlist/internal/interceptors"
+	"github.com/watchlist-kata/watchlist/internal/metrics"
+	"github.com/watchlist-kata/watchlist/internal/ratelimit"
 	"github.com/watchlist-kata/watchlist/internal/repository"
 	"github.com/watchlist-kata/watchlist/internal/service"
+	"github.com/watchlist-kata/watchlist/internal/tlsconfig"
+	applogger "github.com/watchlist-kata/watchlist/pkg/logger"
 	"github.com/watchlist-kata/watchlist/pkg/utils"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
-// RunServer запускает gRPC сервер
-func RunServer(cfg *config.Config, logger *slog.Logger) error {
+// KafkaLoggerHealthService — имя сервиса в gRPC health, отражающее состояние отправки логов в Kafka
+const KafkaLoggerHealthService = "watchlist.KafkaLogger"
+
+// RunServer запускает gRPC сервер и блокируется до отмены контекста или ошибки сервера.
+// При отмене контекста сервер завершает обрабатываемые запросы в пределах cfg.ShutdownTimeout,
+// после чего останавливаются фоновые задачи и закрывается пул соединений с базой данных.
+// Уровень логов, лимиты запросов и переключатели функций берутся из действующей конфигурации
+// configReloader и применяются без перезапуска при каждой успешной перезагрузке.
+func RunServer(ctx context.Context, configReloader *config.Reloader, logger *slog.Logger) error {
+	cfg := configReloader.Current()
+
+	// Административный сервер закрывается последним, после пула соединений и фоновых задач,
+	// чтобы метрики были доступны во время остановки; отложенный вызов регистрируется первым
+	var adminServer *http.Server
+	defer func() {
+		if adminServer != nil {
+			adminServer.Close()
+		}
+	}()
+
 	// Подключение к базе данных
-	db, err := utils.ConnectToDatabase(cfg)
+	db, err := utils.ConnectToDatabase(ctx, cfg, logger)
 	if err != nil {
 		logger.Error("failed to connect to database", slog.Any("error", err))
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	sqlDB, err := db.DB()
+	if err != nil {
+		logger.Error("failed to get database handle", slog.Any("error", err))
+		return fmt.Errorf("failed to get database handle: %w", err)
+	}
+	defer func() {
+		if err := sqlDB.Close(); err != nil {
+			logger.Error("failed to close database connection pool", slog.Any("error", err))
+		}
+	}()
+
+	// Метрики запросов, пула соединений и буферов логгера
+	appMetrics := metrics.New()
+	if err := appMetrics.InstrumentGorm(db); err != nil {
+		logger.Error("failed to instrument database queries", slog.Any("error", err))
+		return fmt.Errorf("failed to instrument database queries: %w", err)
+	}
+	appMetrics.RegisterDBStats(sqlDB)
+
+	// Фоновая проверка соединения с базой данных
+	dbMonitor := utils.NewDBMonitor(sqlDB, cfg.DBPingInterval, logger)
+	dbMonitor.Start()
+	defer dbMonitor.Stop()
+
 	// Создание репозитория
 	repo := repository.NewPostgresRepository(db, logger)
 
@@ -35,14 +92,213 @@ func RunServer(cfg *config.Config, logger *slog.Logger) error {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
 
-	s := grpc.NewServer()
+	// Проверки состояния зависимостей: база данных и схема определяют готовность сервиса,
+	// состояние Kafka-логгера публикуется отдельно и не влияет на общий статус:
+	// пока Kafka недоступна, сервис работает в деградированном режиме и переподключается в фоне
+	healthChecker := healthcheck.NewChecker(cfg.HealthCheckInterval, logger)
+	healthChecker.AddService(watchlist.WatchlistService_ServiceDesc.ServiceName, true, sqlDB.PingContext, repo.CheckSchema)
+	multiHandler, hasMultiHandler := applogger.MultiHandlerFrom(logger)
+	var logLevels *admin.Levels
+	if hasMultiHandler {
+		logLevels = admin.NewLevels(multiHandler, logger)
+		setLogLevels(logLevels, cfg)
+		appMetrics.RegisterLogger(multiHandler)
+		healthChecker.AddService(KafkaLoggerHealthService, false, func(context.Context) error {
+			return multiHandler.Err()
+		})
+	}
+	healthChecker.Start()
+	defer healthChecker.Stop()
+
+	// Язык сообщений для клиентов определяется по метаданным accept-language
+	localeResolver, err := i18n.NewResolver(cfg.DefaultLocale)
+	if err != nil {
+		logger.Error("invalid default locale", slog.Any("error", err))
+		return fmt.Errorf("invalid default locale: %w", err)
+	}
+
+	// Журнал вызовов можно отключить без перезапуска через FEATURES
+	var accessLogEnabled atomic.Bool
+	accessLogEnabled.Store(cfg.Features.Enabled(config.FeatureAccessLog))
+
+	// Перехватчики, доступные для цепочки; выключенные в конфигурации компоненты в цепочку не попадают
+	available := map[string]grpc.UnaryServerInterceptor{
+		interceptorRecovery:  interceptors.Recovery(logger),
+		interceptorRequestID: interceptors.RequestID(),
+		interceptorLocale:    localeResolver.UnaryServerInterceptor(),
+		interceptorMetrics:   appMetrics.UnaryServerInterceptor(),
+		interceptorAccessLog: interceptors.AccessLog(logger, accessLogEnabled.Load),
+	}
+
+	// Аутентификация по JWT: пользователь из токена может работать только со своим списком
+	if cfg.AuthEnabled {
+		keys, err := auth.NewKeySet(cfg.AuthJWKSFile, cfg.AuthStaticKeyFile, cfg.AuthKeysRefresh)
+		if err != nil {
+			logger.Error("failed to load authentication keys", slog.Any("error", err))
+			return fmt.Errorf("failed to load authentication keys: %w", err)
+		}
+		authenticator := auth.NewAuthenticator(keys, auth.Options{
+			Issuer:     cfg.AuthIssuer,
+			Audience:   cfg.AuthAudience,
+			AdminScope: cfg.AuthAdminScope,
+		}, logger)
+		available[interceptorAuth] = authenticator.UnaryServerInterceptor()
+	}
+
+	// Ограничение частоты запросов на пользователя и на клиента. Перехватчик создается и без правил,
+	// чтобы лимиты можно было включить перезагрузкой конфигурации.
+	var limiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+	if cfg.RateLimitBackend == "postgres" {
+		limiter, err = ratelimit.NewPostgresLimiter(ctx, db, logger)
+		if err != nil {
+			logger.Error("failed to create shared rate limiter", slog.Any("error", err))
+			return fmt.Errorf("failed to create shared rate limiter: %w", err)
+		}
+	}
+	rateLimiter := ratelimit.NewInterceptor(limiter, rateLimitRules(cfg.RateLimitUser), rateLimitRules(cfg.RateLimitClient), logger)
+	available[interceptorRateLimit] = rateLimiter.UnaryServerInterceptor()
+
+	// Применение параметров с тегом reload после перезагрузки конфигурации
+	configReloader.Subscribe(func(cfg *config.Config) {
+		if logLevels != nil {
+			setLogLevels(logLevels, cfg)
+		}
+		rateLimiter.SetRules(rateLimitRules(cfg.RateLimitUser), rateLimitRules(cfg.RateLimitClient))
+		accessLogEnabled.Store(cfg.Features.Enabled(config.FeatureAccessLog))
+	})
+
+	// TLS публичного сервера с повторной загрузкой сертификатов и проверкой клиентов по SAN
+	var publicOpts []grpc.ServerOption
+	if cfg.TLSCertFile != "" {
+		reloader, err := tlsconfig.NewReloader(tlsconfig.Options{
+			CertFile:       cfg.TLSCertFile,
+			KeyFile:        cfg.TLSKeyFile,
+			ClientCAFile:   cfg.TLSClientCAFile,
+			ClientAuth:     cfg.TLSClientAuth,
+			ReloadInterval: cfg.TLSReloadInterval,
+		}, logger)
+		if err != nil {
+			logger.Error("failed to load TLS configuration", slog.Any("error", err))
+			return fmt.Errorf("failed to load TLS configuration: %w", err)
+		}
+		publicOpts = append(publicOpts, grpc.Creds(credentials.NewTLS(reloader.ServerConfig())))
+		available[interceptorTLSClient] = auth.ClientCertInterceptor(cfg.TLSAllowedClients, logger)
+	}
+
+	// Цепочки перехватчиков в порядке из конфигурации. Проверка сертификата клиента выполняется
+	// только на публичном сервере, шлюз обращается к своему серверу без TLS. Конфигурация
+	// не допускает включения шлюза вместе с проверкой клиентов, поэтому обойти её через шлюз нельзя.
+	publicChain, err := buildInterceptorChain(cfg.GRPCInterceptors, available, nil)
+	if err != nil {
+		logger.Error("invalid interceptor chain", slog.Any("error", err))
+		return fmt.Errorf("invalid interceptor chain: %w", err)
+	}
+	gatewayChain, err := buildInterceptorChain(cfg.GRPCInterceptors, available, []string{interceptorTLSClient})
+	if err != nil {
+		logger.Error("invalid interceptor chain", slog.Any("error", err))
+		return fmt.Errorf("invalid interceptor chain: %w", err)
+	}
+
+	publicOpts = append(publicOpts,
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(publicChain...),
+	)
+	gatewayOpts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(gatewayChain...),
+	}
+
+	s := grpc.NewServer(publicOpts...)
 	watchlist.RegisterWatchlistServiceServer(s, svc)
+	healthpb.RegisterHealthServer(s, healthChecker.Server())
+
+	// Ошибки работы gRPC сервера, серверов REST шлюза и административного сервера
+	serveErr := make(chan error, 4)
+
+	// Запуск административного сервера с метриками, если задан порт
+	if cfg.AdminPort != "" {
+		adminServer, err = startAdminServer(cfg.AdminPort, admin.NewHandler(appMetrics.Handler(), logLevels, cfg.AdminToken), logger, serveErr)
+		if err != nil {
+			logger.Error("failed to start admin server", slog.Any("error", err))
+			return fmt.Errorf("failed to start admin server: %w", err)
+		}
+	}
+
+	// Запуск REST шлюза, если задан порт
+	var restGW *restGateway
+	if cfg.HTTPPort != "" {
+		restGW, err = startGateway(cfg.HTTPPort, svc, gatewayOpts, logger, serveErr)
+		if err != nil {
+			logger.Error("failed to start REST gateway", slog.Any("error", err))
+			return fmt.Errorf("failed to start REST gateway: %w", err)
+		}
+	}
 
 	logger.Info("starting gRPC server", slog.String("port", cfg.GRPCPort))
 	fmt.Printf("Starting gRPC server on %s\n", cfg.GRPCPort)
-	if err := s.Serve(lis); err != nil {
-		logger.Error("failed to serve", slog.Any("error", err))
-		return fmt.Errorf("failed to serve: %w", err)
+
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			serveErr <- err
+		}
+	}()
+
+	var runErr error
+	select {
+	case runErr = <-serveErr:
+		logger.Error("failed to serve", slog.Any("error", runErr))
+		runErr = fmt.Errorf("failed to serve: %w", runErr)
+	case <-ctx.Done():
+		logger.Info("shutting down gRPC server", slog.Duration("timeout", cfg.ShutdownTimeout))
+	}
+
+	healthChecker.Shutdown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if restGW != nil {
+		restGW.Shutdown(shutdownCtx)
+	}
+	gracefulStop(shutdownCtx, s, logger)
+	logger.Info("gRPC server stopped")
+	return runErr
+}
+
+// setLogLevels применяет уровни логов из конфигурации: общий минимальный уровень и уровни обработчиков.
+// Уровни, измененные через административный сервер, сохраняются до возврата.
+func setLogLevels(levels *admin.Levels, cfg *config.Config) {
+	levels.Configure(map[string]slog.Level{
+		applogger.AllHandlers: cfg.LogLevel,
+		"kafka":               cfg.LogLevelKafka,
+		"file":                cfg.LogLevelFile,
+		"stdout":              cfg.LogLevelStdout,
+	})
+}
+
+// rateLimitRules преобразует лимиты из конфигурации в правила ограничителя
+func rateLimitRules(limits map[string]config.RateLimit) map[string]ratelimit.Rule {
+	rules := make(map[string]ratelimit.Rule, len(limits))
+	for method, limit := range limits {
+		rules[method] = ratelimit.Rule{Rate: limit.Rate, Burst: limit.Burst}
+	}
+	return rules
+}
+
+// gracefulStop дожидается завершения обрабатываемых запросов, но не дольше отмены ctx,
+// после чего принудительно закрывает оставшиеся соединения
+func gracefulStop(ctx context.Context, s *grpc.Server, logger *slog.Logger) {
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		logger.Warn("graceful shutdown timed out, closing remaining connections")
+		s.Stop()
+		<-stopped
 	}
-	return nil
 }
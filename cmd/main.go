@@ -1,32 +1,21 @@
 package main
 
 import (
-	"github.com/watchlist-kata/watchlist/api/server"
-	"github.com/watchlist-kata/watchlist/internal/config"
-	"github.com/watchlist-kata/watchlist/pkg/logger"
-	"log"
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/watchlist-kata/watchlist/internal/cli"
 )
 
 func main() {
-	// Загрузка конфигурации
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		log.Fatal(err)
-	}
+	// Контекст отменяется при получении SIGINT или SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 
-	// Инициализация кастомного логгера
-	customLogger, err := logger.NewLogger(cfg.KafkaBrokers, cfg.KafkaTopic, cfg.ServiceName, cfg.LogBufferSize)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer func() {
-		if multiHandler, ok := customLogger.Handler().(*logger.MultiHandler); ok {
-			multiHandler.CloseAll()
-		}
-	}()
+	// Запуск подкоманды: serve, migrate, export-user, import, purge-user, stats или check-config
+	code := cli.Run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
 
-	// Запуск сервера
-	if err = server.RunServer(cfg, customLogger); err != nil {
-		log.Fatal(err)
-	}
+	os.Exit(code)
 }
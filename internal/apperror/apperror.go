@@ -0,0 +1,113 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"github.com/watchlist-kata/watchlist/internal/i18n"
+)
+
+// Domain — домен ошибок сервиса в деталях ErrorInfo
+const Domain = "watchlist.watchlist-kata"
+
+// Code — машиночитаемый код ошибки, передается клиенту в ErrorInfo.Reason
+type Code string
+
+// Коды ошибок сервиса
+const (
+	CodeInvalidArgument  Code = "INVALID_ARGUMENT"
+	CodeNotFound         Code = "NOT_FOUND"
+	CodeAlreadyExists    Code = "ALREADY_EXISTS"
+	CodeUnauthenticated  Code = "UNAUTHENTICATED"
+	CodePermissionDenied Code = "PERMISSION_DENIED"
+	CodeClientNotAllowed Code = "CLIENT_NOT_ALLOWED"
+	CodeRateLimited      Code = "RATE_LIMITED"
+	CodeCanceled         Code = "CANCELED"
+	CodeDeadlineExceeded Code = "DEADLINE_EXCEEDED"
+	CodeUnavailable      Code = "UNAVAILABLE"
+	CodeInternal         Code = "INTERNAL"
+)
+
+// FieldViolation — описание ошибки в поле запроса
+type FieldViolation struct {
+	Field string
+	Key   i18n.Key // Описание ошибки в каталоге сообщений
+}
+
+// Error — ошибка сервиса с кодом, сообщением для клиента и дополнительными деталями.
+// Сообщение хранится ключом каталога и переводится на язык клиента при отправке ответа.
+type Error struct {
+	Code       Code
+	Key        i18n.Key          // Сообщение для клиента
+	Params     i18n.Params       // Параметры сообщения
+	Violations []FieldViolation  // Ошибки в полях запроса
+	RetryAfter time.Duration     // Рекомендуемая задержка перед повтором (0 — повтор не рекомендуется)
+	Metadata   map[string]string // Дополнительные сведения для ErrorInfo
+	Err        error             // Исходная ошибка, клиенту не передается
+}
+
+// New создает ошибку с кодом и сообщением для клиента
+func New(code Code, key i18n.Key) *Error {
+	return &Error{Code: code, Key: key}
+}
+
+// Wrap создает ошибку с кодом и сообщением для клиента поверх исходной ошибки
+func Wrap(code Code, key i18n.Key, err error) *Error {
+	return &Error{Code: code, Key: key, Err: err}
+}
+
+// Validation создает ошибку проверки входных данных с описанием ошибок в полях
+func Validation(key i18n.Key, violations ...FieldViolation) *Error {
+	return &Error{Code: CodeInvalidArgument, Key: key, Violations: violations}
+}
+
+// WithParams задает параметры сообщения
+func (e *Error) WithParams(params i18n.Params) *Error {
+	e.Params = params
+	return e
+}
+
+// WithRetryAfter задает рекомендуемую задержку перед повтором
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	e.RetryAfter = d
+	return e
+}
+
+// WithMetadata добавляет сведения в ErrorInfo
+func (e *Error) WithMetadata(key, value string) *Error {
+	if e.Metadata == nil {
+		e.Metadata = make(map[string]string)
+	}
+	e.Metadata[key] = value
+	return e
+}
+
+// Message возвращает сообщение для клиента на языке locale
+func (e *Error) Message(locale language.Tag) string {
+	return i18n.Translate(locale, e.Key, e.Params)
+}
+
+// Error возвращает текст ошибки на английском вместе с исходной ошибкой, текст предназначен для логов
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message(language.English), e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message(language.English))
+}
+
+// Unwrap возвращает исходную ошибку
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// CodeOf возвращает код ошибки сервиса или CodeInternal для прочих ошибок
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return CodeInternal
+}
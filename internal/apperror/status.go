@@ -0,0 +1,109 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/watchlist-kata/watchlist/internal/i18n"
+	"github.com/watchlist-kata/watchlist/internal/repository"
+)
+
+// migrationsRetryAfter — рекомендуемая задержка перед повтором, пока схема базы данных не создана
+const migrationsRetryAfter = 5 * time.Second
+
+// grpcCodes сопоставляет коды ошибок сервиса кодам gRPC
+var grpcCodes = map[Code]codes.Code{
+	CodeInvalidArgument:  codes.InvalidArgument,
+	CodeNotFound:         codes.NotFound,
+	CodeAlreadyExists:    codes.AlreadyExists,
+	CodeUnauthenticated:  codes.Unauthenticated,
+	CodePermissionDenied: codes.PermissionDenied,
+	CodeClientNotAllowed: codes.PermissionDenied,
+	CodeRateLimited:      codes.ResourceExhausted,
+	CodeCanceled:         codes.Canceled,
+	CodeDeadlineExceeded: codes.DeadlineExceeded,
+	CodeUnavailable:      codes.Unavailable,
+	CodeInternal:         codes.Internal,
+}
+
+// From приводит любую ошибку к ошибке сервиса: ошибки репозитория и контекста получают
+// свои коды, остальные считаются внутренними
+func From(err error) *Error {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, context.Canceled):
+		return Wrap(CodeCanceled, i18n.KeyCanceled, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return Wrap(CodeDeadlineExceeded, i18n.KeyDeadlineExceeded, err)
+	case errors.Is(err, repository.ErrRecordNotFound):
+		return Wrap(CodeNotFound, i18n.KeyNotFound, err)
+	case errors.Is(err, repository.ErrDuplicateEntry):
+		return Wrap(CodeAlreadyExists, i18n.KeyAlreadyExists, err)
+	case errors.Is(err, repository.ErrMigrationsPending):
+		return Wrap(CodeUnavailable, i18n.KeyUnavailable, err).WithRetryAfter(migrationsRetryAfter)
+	default:
+		return Wrap(CodeInternal, i18n.KeyInternal, err)
+	}
+}
+
+// ToStatus переводит ошибку в gRPC статус с деталями ErrorInfo, BadRequest, RetryInfo и LocalizedMessage.
+// Сообщения переводятся на язык запроса из контекста.
+// Ошибки, которые уже являются gRPC статусами, возвращаются без изменений.
+func ToStatus(ctx context.Context, err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if st, ok := status.FromError(err); ok && !errors.As(err, &e) {
+		return st
+	}
+
+	e = From(err)
+	locale := i18n.LocaleFromContext(ctx)
+	message := e.Message(locale)
+	code, ok := grpcCodes[e.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{
+		Reason:   string(e.Code),
+		Domain:   Domain,
+		Metadata: e.Metadata,
+	}}
+	if len(e.Violations) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, v := range e.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: i18n.Translate(locale, v.Key, nil),
+			})
+		}
+		details = append(details, badRequest)
+	}
+	if e.RetryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(e.RetryAfter)})
+	}
+
+	details = append(details, &errdetails.LocalizedMessage{Locale: locale.String(), Message: message})
+
+	st := status.New(code, message)
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		return withDetails
+	}
+	return st
+}
+
+// ToGRPC переводит ошибку в ошибку gRPC, см. ToStatus
+func ToGRPC(ctx context.Context, err error) error {
+	return ToStatus(ctx, err).Err()
+}
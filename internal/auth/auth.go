@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/watchlist-kata/watchlist/internal/apperror"
+	"github.com/watchlist-kata/watchlist/internal/i18n"
+	"github.com/watchlist-kata/watchlist/pkg/logger"
+)
+
+// validMethods — допустимые алгоритмы подписи токенов
+var validMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "HS256", "HS384", "HS512"}
+
+// publicMethodPrefixes — методы, не требующие аутентификации
+var publicMethodPrefixes = []string{"/grpc.health.v1.Health/"}
+
+// Identity описывает аутентифицированного клиента
+type Identity struct {
+	Subject string   // Субъект токена (sub)
+	UserID  int64    // Идентификатор пользователя, полученный из субъекта
+	Scopes  []string // Области доступа токена
+	Admin   bool     // Токен содержит административную область доступа
+	Client  string   // Идентификатор клиента из сертификата mTLS, если он был предъявлен
+}
+
+// identityKey — ключ контекста для Identity
+type identityKey struct{}
+
+// WithIdentity возвращает контекст с информацией об аутентифицированном клиенте
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext возвращает информацию об аутентифицированном клиенте, если она есть
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok
+}
+
+// identityRecorderKey — ключ контекста для identityRecorder
+type identityRecorderKey struct{}
+
+// identityRecorder сохраняет Identity, установленную перехватчиком аутентификации глубже по цепочке
+type identityRecorder struct {
+	identity *Identity
+}
+
+// WithIdentityRecorder возвращает контекст, в который перехватчик аутентификации запишет Identity клиента,
+// и функцию для ее получения после вызова обработчика. Нужен перехватчикам, стоящим в цепочке до
+// аутентификации, например журналу доступа.
+func WithIdentityRecorder(ctx context.Context) (context.Context, func() (*Identity, bool)) {
+	recorder := &identityRecorder{}
+	return context.WithValue(ctx, identityRecorderKey{}, recorder), func() (*Identity, bool) {
+		return recorder.identity, recorder.identity != nil
+	}
+}
+
+// userIDRequest — запрос, относящийся к списку просмотра конкретного пользователя
+type userIDRequest interface {
+	GetUserId() int64
+}
+
+// Options содержит параметры проверки токенов
+type Options struct {
+	Issuer     string // Ожидаемый издатель (iss), пустое значение не проверяется
+	Audience   string // Ожидаемая аудитория (aud), пустое значение не проверяется
+	AdminScope string // Область доступа, разрешающая работу со списками любых пользователей
+}
+
+// Authenticator проверяет bearer JWT и связывает запрос с пользователем из токена
+type Authenticator struct {
+	keys   *KeySet
+	opts   Options
+	parser *jwt.Parser
+	logger *slog.Logger
+}
+
+// NewAuthenticator создает новый экземпляр Authenticator
+func NewAuthenticator(keys *KeySet, opts Options, logger *slog.Logger) *Authenticator {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(validMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+
+	return &Authenticator{
+		keys:   keys,
+		opts:   opts,
+		parser: jwt.NewParser(parserOpts...),
+		logger: logger,
+	}
+}
+
+// UnaryServerInterceptor проверяет токен из метаданных authorization и отклоняет запросы
+// к спискам других пользователей, если у токена нет административной области доступа
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		for _, prefix := range publicMethodPrefixes {
+			if strings.HasPrefix(info.FullMethod, prefix) {
+				return handler(ctx, req)
+			}
+		}
+
+		identity, err := a.authenticate(ctx)
+		if err != nil {
+			a.logger.WarnContext(ctx, fmt.Sprintf("authentication failed for %s", info.FullMethod), slog.Any("error", err))
+			return nil, apperror.ToGRPC(ctx, apperror.New(apperror.CodeUnauthenticated, i18n.KeyUnauthenticated))
+		}
+		if recorder, ok := ctx.Value(identityRecorderKey{}).(*identityRecorder); ok {
+			recorder.identity = identity
+		}
+
+		if r, ok := req.(userIDRequest); ok && !identity.Admin && r.GetUserId() != identity.UserID {
+			a.logger.WarnContext(ctx, fmt.Sprintf("user ID: %d is not allowed to access watchlist of user ID: %d", identity.UserID, r.GetUserId()))
+			return nil, apperror.ToGRPC(ctx, apperror.New(apperror.CodePermissionDenied, i18n.KeyPermissionDenied))
+		}
+
+		// Идентификатор пользователя попадает во все записи логов этого вызова
+		return handler(logger.WithUserID(WithIdentity(ctx, identity), identity.UserID), req)
+	}
+}
+
+// authenticate извлекает и проверяет bearer токен из метаданных запроса
+func (a *Authenticator) authenticate(ctx context.Context) (*Identity, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, fmt.Errorf("missing authorization metadata")
+	}
+
+	scheme, rawToken, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, fmt.Errorf("authorization metadata is not a bearer token")
+	}
+
+	claims := &tokenClaims{}
+	if _, err := a.parser.ParseWithClaims(strings.TrimSpace(rawToken), claims, a.keyFunc); err != nil {
+		return nil, err
+	}
+
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil || userID <= 0 {
+		return nil, fmt.Errorf("token subject %q is not a user ID", claims.Subject)
+	}
+
+	scopes := claims.scopes()
+	identity := &Identity{Subject: claims.Subject, UserID: userID, Scopes: scopes}
+	identity.Client, _ = ClientFromContext(ctx)
+	for _, scope := range scopes {
+		if a.opts.AdminScope != "" && scope == a.opts.AdminScope {
+			identity.Admin = true
+		}
+	}
+	return identity, nil
+}
+
+// keyFunc выбирает ключ проверки подписи по заголовку kid
+func (a *Authenticator) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	return a.keys.Key(kid)
+}
+
+// tokenClaims — поля токена, используемые сервисом
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Scope string   `json:"scope,omitempty"` // Области доступа через пробел (RFC 8693)
+	Scp   []string `json:"scp,omitempty"`   // Области доступа списком
+}
+
+// scopes возвращает все области доступа токена
+func (c *tokenClaims) scopes() []string {
+	return append(strings.Fields(c.Scope), c.Scp...)
+}
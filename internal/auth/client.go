@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/watchlist-kata/watchlist/internal/apperror"
+	"github.com/watchlist-kata/watchlist/internal/i18n"
+)
+
+// clientKey — ключ контекста для идентификатора клиента из сертификата
+type clientKey struct{}
+
+// WithClient возвращает контекст с идентификатором клиента, подтверждённым сертификатом mTLS
+func WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFromContext возвращает идентификатор клиента, подтверждённый сертификатом mTLS, если он есть
+func ClientFromContext(ctx context.Context) (string, bool) {
+	client, ok := ctx.Value(clientKey{}).(string)
+	return client, ok
+}
+
+// ClientCertInterceptor извлекает идентификатор клиента из SAN проверенного сертификата mTLS
+// и сохраняет его в контексте. Если список allowed не пуст, запросы клиентов без сертификата
+// или с SAN не из списка отклоняются с PermissionDenied.
+func ClientCertInterceptor(allowed []string, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	allowedSet := make(map[string]struct{}, len(allowed))
+	for _, name := range allowed {
+		allowedSet[name] = struct{}{}
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		names := verifiedClientNames(ctx)
+
+		if len(allowedSet) == 0 {
+			if len(names) > 0 {
+				ctx = WithClient(ctx, names[0])
+			}
+			return handler(ctx, req)
+		}
+
+		for _, name := range names {
+			if _, ok := allowedSet[name]; ok {
+				return handler(WithClient(ctx, name), req)
+			}
+		}
+
+		logger.WarnContext(ctx, fmt.Sprintf("client certificate is not allowed for %s", info.FullMethod), slog.Any("names", names))
+		return nil, apperror.ToGRPC(ctx, apperror.New(apperror.CodeClientNotAllowed, i18n.KeyClientNotAllowed))
+	}
+}
+
+// verifiedClientNames возвращает имена из SAN (DNS, URI, email) проверенного сертификата клиента
+func verifiedClientNames(ctx context.Context) []string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return certificateNames(tlsInfo.State.VerifiedChains[0][0])
+}
+
+// certificateNames возвращает имена из SAN сертификата
+func certificateNames(cert *x509.Certificate) []string {
+	names := make([]string, 0, len(cert.DNSNames)+len(cert.URIs)+len(cert.EmailAddresses))
+	names = append(names, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	names = append(names, cert.EmailAddresses...)
+	return names
+}
@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrKeyNotFound возвращается, когда для токена не найден ключ проверки подписи
+	ErrKeyNotFound = errors.New("signing key not found")
+)
+
+// KeySet хранит ключи проверки подписи JWT. Ключи читаются из JWKS файла или из файла
+// со статическим ключом. JWKS файл перечитывается при изменении, что позволяет ротировать ключи без перезапуска.
+type KeySet struct {
+	jwksFile      string
+	staticKeyFile string
+	refresh       time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]interface{} // ключи JWKS по kid
+	staticKey interface{}
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// NewKeySet создает KeySet и загружает ключи. Должен быть задан хотя бы один из файлов.
+func NewKeySet(jwksFile, staticKeyFile string, refresh time.Duration) (*KeySet, error) {
+	if jwksFile == "" && staticKeyFile == "" {
+		return nil, errors.New("either JWKS file or static key file must be set")
+	}
+
+	ks := &KeySet{jwksFile: jwksFile, staticKeyFile: staticKeyFile, refresh: refresh}
+
+	if staticKeyFile != "" {
+		key, err := loadStaticKey(staticKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		ks.staticKey = key
+	}
+
+	if jwksFile != "" {
+		if err := ks.reload(true); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+// Key возвращает ключ для проверки подписи токена с указанным kid.
+// JWKS файл проверяется на изменения не чаще интервала обновления.
+func (ks *KeySet) Key(kid string) (interface{}, error) {
+	if ks.jwksFile != "" {
+		if err := ks.reload(false); err != nil {
+			return nil, err
+		}
+		if key, ok := ks.lookup(kid); ok {
+			return key, nil
+		}
+	}
+
+	if ks.staticKey != nil && kid == "" {
+		return ks.staticKey, nil
+	}
+	return nil, fmt.Errorf("%w: kid %q", ErrKeyNotFound, kid)
+}
+
+// lookup ищет ключ JWKS по kid. Токен без kid принимается, только если в наборе один ключ.
+func (ks *KeySet) lookup(kid string) (interface{}, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if kid == "" {
+		if len(ks.keys) == 1 && ks.staticKey == nil {
+			for _, key := range ks.keys {
+				return key, true
+			}
+		}
+		return nil, false
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// reload перечитывает JWKS файл, если с последней проверки прошло не меньше интервала обновления
+// и время изменения файла поменялось. При force файл читается без этих условий.
+func (ks *KeySet) reload(force bool) error {
+	ks.mu.RLock()
+	due := force || time.Since(ks.checkedAt) >= ks.refresh
+	ks.mu.RUnlock()
+	if !due {
+		return nil
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.checkedAt = time.Now()
+	info, err := os.Stat(ks.jwksFile)
+	if err != nil {
+		if ks.keys != nil {
+			// Оставляем ранее загруженные ключи, если файл временно недоступен
+			return nil
+		}
+		return fmt.Errorf("failed to stat JWKS file: %w", err)
+	}
+	if !force && info.ModTime().Equal(ks.modTime) {
+		return nil
+	}
+
+	keys, err := loadJWKS(ks.jwksFile)
+	if err != nil {
+		if ks.keys != nil {
+			return nil
+		}
+		return err
+	}
+	ks.keys = keys
+	ks.modTime = info.ModTime()
+	return nil
+}
+
+// jsonWebKey — ключ в формате JWK (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// loadJWKS читает JWKS файл и возвращает ключи подписи по kid
+func loadJWKS(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS file: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWK %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+// publicKey преобразует JWK в ключ, пригодный для проверки подписи
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, err
+		}
+		return secret, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// decodeBigInt декодирует число в base64url без дополнения
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// loadStaticKey читает статический ключ: открытый ключ RSA/ECDSA в PEM или секрет HMAC
+func loadStaticKey(path string) (interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read static key file: %w", err)
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse static public key: %w", err)
+		}
+		return key, nil
+	}
+
+	secret := bytes.TrimSpace(data)
+	if len(secret) == 0 {
+		return nil, errors.New("static key file is empty")
+	}
+	return secret, nil
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/watchlist-kata/watchlist/api/server"
+	"github.com/watchlist-kata/watchlist/internal/config"
+	"github.com/watchlist-kata/watchlist/pkg/logger"
+)
+
+// checkConfigResult — результат проверки конфигурации
+type checkConfigResult struct {
+	Command string           `json:"command"`
+	DryRun  bool             `json:"dry_run"`
+	Valid   bool             `json:"valid"`
+	Errors  []string         `json:"errors,omitempty"`
+	Config  []config.Setting `json:"config,omitempty"` // Действующая конфигурация со скрытыми секретами
+}
+
+// runCheckConfig проверяет конфигурацию без подключения к базе данных и Kafka.
+// Команда ничего не изменяет, поэтому --dry-run на нее не влияет.
+func runCheckConfig(_ context.Context, e *env, args []string) error {
+	fs, dryRun := e.newFlagSet("check-config")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	return checkConfig(e, "check-config", *dryRun)
+}
+
+// checkConfig загружает и проверяет конфигурацию, выводит действующие значения параметров
+// и все найденные ошибки и завершает команду с кодом ExitConfig, если они есть
+func checkConfig(e *env, name string, dryRun bool) error {
+	result := checkConfigResult{Command: name, DryRun: dryRun, Valid: true}
+
+	cfg, err := config.Load(e.config.Options())
+	if err == nil {
+		result.Config = cfg.Redacted()
+		err = server.CheckConfig(cfg, slog.New(logger.NewStderrHandler()))
+	}
+	if err != nil {
+		result.Valid = false
+		result.Errors = splitErrors(err)
+	}
+
+	if err := writeJSON(e.stdout, result); err != nil {
+		return err
+	}
+	if !result.Valid {
+		return withExitCode(ExitConfig, errReported)
+	}
+	return nil
+}
+
+// splitErrors раскладывает ошибки, объединенные errors.Join, в список строк
+func splitErrors(err error) []string {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var messages []string
+		for _, e := range joined.Unwrap() {
+			messages = append(messages, splitErrors(e)...)
+		}
+		return messages
+	}
+	return []string{err.Error()}
+}
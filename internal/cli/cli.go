@@ -0,0 +1,301 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/watchlist-kata/watchlist/internal/config"
+	"github.com/watchlist-kata/watchlist/internal/repository"
+	"github.com/watchlist-kata/watchlist/pkg/logger"
+	"github.com/watchlist-kata/watchlist/pkg/utils"
+)
+
+// Коды завершения команд, на которые могут опираться скрипты
+const (
+	ExitOK          = 0 // Команда выполнена
+	ExitFailure     = 1 // Ошибка при выполнении команды
+	ExitUsage       = 2 // Неверные аргументы командной строки или входные данные
+	ExitConfig      = 3 // Неверная конфигурация
+	ExitUnavailable = 4 // База данных недоступна
+)
+
+// command — подкоманда CLI
+type command struct {
+	summary string
+	run     func(ctx context.Context, env *env, args []string) error
+}
+
+// commands — подкоманды по имени
+var commands = map[string]command{
+	"serve":        {"запустить gRPC сервер", runServe},
+	"migrate":      {"создать или обновить схему базы данных", runMigrate},
+	"export-user":  {"выгрузить список просмотра пользователя в JSON", runExportUser},
+	"import":       {"загрузить списки просмотра из JSON, выгруженного export-user", runImport},
+	"purge-user":   {"удалить весь список просмотра пользователя", runPurgeUser},
+	"stats":        {"вывести сводные данные по спискам просмотра", runStats},
+	"check-config": {"проверить конфигурацию без запуска сервера", runCheckConfig},
+}
+
+// env — окружение выполнения команды
+type env struct {
+	stdout io.Writer
+	stderr io.Writer
+	stdin  io.Reader
+	config *config.Flags // Флаги конфигурации подкоманды
+}
+
+// exitError — ошибка команды с кодом завершения
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// errReported означает, что команда уже вывела результат с описанием ошибки
+var errReported = errors.New("error is reported in the command result")
+
+// withExitCode связывает ошибку с кодом завершения
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// Run выполняет подкоманду из args и возвращает код завершения.
+// Без аргументов выполняется serve, чтобы запуск бинарного файла без параметров работал как раньше.
+// Результат команды выводится в stdout одной строкой JSON, логи пишутся в stderr.
+func Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	e := &env{stdout: stdout, stderr: stderr, stdin: stdin}
+
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage(stderr)
+		return ExitOK
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", name)
+		printUsage(stderr)
+		return ExitUsage
+	}
+
+	err := cmd.run(ctx, e, args)
+	if err == nil {
+		return ExitOK
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+
+	code := ExitFailure
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		code = exitErr.code
+	}
+	if !errors.Is(err, errReported) {
+		writeJSON(stdout, map[string]interface{}{"command": name, "error": err.Error(), "exit_code": code})
+	}
+	return code
+}
+
+// printUsage выводит список подкоманд
+func printUsage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "usage: watchlist <command> [flags]")
+	fmt.Fprintln(w, "commands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-14s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w, "every command accepts --dry-run; run watchlist <command> -h for its flags")
+}
+
+// newFlagSet создает набор флагов подкоманды с общим флагом --dry-run и флагами конфигурации
+func (e *env) newFlagSet(name string) (*flag.FlagSet, *bool) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	dryRun := fs.Bool("dry-run", false, "показать, что будет сделано, ничего не изменяя")
+	e.config = config.RegisterFlags(fs)
+	return fs, dryRun
+}
+
+// parseFlags разбирает флаги подкоманды; ошибка разбора завершает команду с кодом ExitUsage
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return withExitCode(ExitUsage, err)
+	}
+	if fs.NArg() > 0 {
+		return withExitCode(ExitUsage, fmt.Errorf("unexpected arguments: %v", fs.Args()))
+	}
+	return nil
+}
+
+// requireUserID проверяет, что флаг --user-id задан положительным числом
+func requireUserID(userID int64) error {
+	if userID <= 0 {
+		return withExitCode(ExitUsage, errors.New("--user-id must be a positive integer"))
+	}
+	return nil
+}
+
+// writeJSON выводит результат команды одной строкой JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// loadConfig загружает конфигурацию с учетом флагов подкоманды; ошибка завершает команду с кодом ExitConfig
+func (e *env) loadConfig() (*config.Config, error) {
+	cfg, err := config.Load(e.config.Options())
+	if err != nil {
+		return nil, withExitCode(ExitConfig, err)
+	}
+	return cfg, nil
+}
+
+// session — репозиторий команды обслуживания базы данных
+type session struct {
+	repo  *repository.PostgresRepository
+	close func()
+}
+
+// openSession загружает конфигурацию и подключается к базе данных.
+// Логи команд обслуживания пишутся в файл и stderr, без Kafka.
+func (e *env) openSession(ctx context.Context) (*session, error) {
+	cfg, err := e.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// Ошибки строки подключения и файлов секретов — ошибки конфигурации, а не недоступность базы данных
+	if _, err := utils.ParseDatabaseDSN(cfg); err != nil {
+		return nil, withExitCode(ExitConfig, err)
+	}
+
+	log, err := logger.NewCommandLogger(cfg.ServiceName, cfg.LogBufferSize, logFileOptions(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create logger: %w", err)
+	}
+	closeLogger := func() {
+		if multiHandler, ok := logger.MultiHandlerFrom(log); ok {
+			closeCtx, cancel := context.WithTimeout(context.Background(), cfg.LogFlushTimeout)
+			defer cancel()
+			if err := multiHandler.CloseAll(closeCtx); err != nil {
+				fmt.Fprintf(e.stderr, "failed to flush logs: %v\n", err)
+			}
+		}
+	}
+
+	db, err := utils.ConnectToDatabase(ctx, cfg, log)
+	if err != nil {
+		closeLogger()
+		return nil, withExitCode(ExitUnavailable, fmt.Errorf("failed to connect to database: %w", err))
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		closeLogger()
+		return nil, withExitCode(ExitUnavailable, fmt.Errorf("failed to get database handle: %w", err))
+	}
+
+	return &session{
+		repo: repository.NewPostgresRepository(db, log),
+		close: func() {
+			if err := sqlDB.Close(); err != nil {
+				log.Error("failed to close database connection pool", slog.Any("error", err))
+			}
+			closeLogger()
+		},
+	}, nil
+}
+
+// logFileOptions возвращает параметры файла лога из конфигурации
+func logFileOptions(cfg *config.Config) logger.FileOptions {
+	return logger.FileOptions{
+		Dir:            cfg.LogDir,
+		MaxSize:        int64(cfg.LogFileMaxSize) * 1024 * 1024,
+		RotateInterval: cfg.LogFileRotateInterval,
+		MaxBackups:     cfg.LogFileMaxBackups,
+		MaxAge:         cfg.LogFileMaxAge,
+		Compress:       cfg.LogFileCompress,
+		Queue:          logQueueOptions(cfg, cfg.LogFileOverflow, cfg.LogFileBlockTimeout),
+	}
+}
+
+// logQueueOptions возвращает политику переполнения буфера, выборку и схлопывание повторов
+// асинхронного обработчика логов
+func logQueueOptions(cfg *config.Config, overflow string, blockTimeout time.Duration) logger.QueueOptions {
+	opts := logger.QueueOptions{
+		Overflow:     logger.OverflowPolicy(overflow),
+		BlockTimeout: blockTimeout,
+		DedupWindow:  cfg.LogDedupWindow,
+	}
+	if len(cfg.LogSampling) > 0 {
+		opts.Sampling = logger.SamplingOptions{Tick: cfg.LogSamplingTick, Rules: make(map[slog.Level]logger.SamplingRule)}
+		for level, sampling := range cfg.LogSampling {
+			opts.Sampling.Rules[level] = logger.SamplingRule{Initial: sampling.Initial, Thereafter: sampling.Thereafter}
+		}
+	}
+	return opts
+}
+
+// kafkaLogOptions возвращает параметры обработчика логов Kafka из конфигурации
+func kafkaLogOptions(cfg *config.Config) logger.KafkaOptions {
+	opts := logger.KafkaOptions{
+		ServiceName: cfg.ServiceName,
+		Producer: logger.ProducerOptions{
+			RequiredAcks:  cfg.LogKafkaAcks,
+			Retries:       cfg.LogKafkaRetries,
+			Compression:   cfg.LogKafkaCompression,
+			Linger:        cfg.LogKafkaLinger,
+			BatchBytes:    cfg.LogKafkaBatchSize,
+			BatchMessages: cfg.LogKafkaBatchMessages,
+			TLS: logger.TLSOptions{
+				Enabled:  cfg.LogKafkaTLS,
+				CAFile:   cfg.LogKafkaTLSCAFile,
+				CertFile: cfg.LogKafkaTLSCertFile,
+				KeyFile:  cfg.LogKafkaTLSKeyFile,
+			},
+			SASL: logger.SASLOptions{
+				Mechanism: cfg.LogKafkaSASLMechanism,
+				Username:  cfg.LogKafkaSASLUsername,
+				Password:  cfg.LogKafkaSASLPassword,
+			},
+		},
+		Queue:               logQueueOptions(cfg, cfg.LogKafkaOverflow, cfg.LogKafkaBlockTimeout),
+		ReconnectBackoff:    cfg.LogKafkaReconnectBackoff,
+		ReconnectMaxBackoff: cfg.LogKafkaReconnectMaxBackoff,
+	}
+	if cfg.LogKafkaErrorTopic != "" {
+		opts.Routes = []logger.TopicRoute{{MinLevel: slog.LevelError, Topic: cfg.LogKafkaErrorTopic}}
+	}
+	if cfg.LogKafkaSpoolMaxSize > 0 {
+		dir := cfg.LogKafkaSpoolDir
+		if dir == "" {
+			dir = filepath.Join(cfg.LogDir, cfg.ServiceName, "kafka-spool")
+		}
+		opts.Spool = logger.SpoolOptions{
+			Dir:            dir,
+			MaxSize:        int64(cfg.LogKafkaSpoolMaxSize) * 1024 * 1024,
+			ReplayInterval: cfg.LogKafkaSpoolReplayInterval,
+		}
+	}
+	return opts
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"context"
+	"errors"
+
+	"github.com/watchlist-kata/watchlist/internal/repository"
+)
+
+// migrateResult — результат команды migrate
+type migrateResult struct {
+	Command string `json:"command"`
+	DryRun  bool   `json:"dry_run"`
+	Pending bool   `json:"pending"` // Схема не была создана до запуска команды
+	Applied bool   `json:"applied"` // Миграции применены
+}
+
+// runMigrate создает или обновляет схему базы данных.
+// С --dry-run только сообщает, создана ли схема.
+func runMigrate(ctx context.Context, e *env, args []string) error {
+	fs, dryRun := e.newFlagSet("migrate")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	s, err := e.openSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	result := migrateResult{Command: "migrate", DryRun: *dryRun}
+	if err := s.repo.CheckSchema(ctx); err != nil {
+		if !errors.Is(err, repository.ErrMigrationsPending) {
+			return err
+		}
+		result.Pending = true
+	}
+
+	if !*dryRun {
+		if err := s.repo.Migrate(ctx); err != nil {
+			return err
+		}
+		result.Applied = true
+	}
+
+	return writeJSON(e.stdout, result)
+}
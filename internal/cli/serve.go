@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/watchlist-kata/watchlist/api/server"
+	"github.com/watchlist-kata/watchlist/internal/config"
+	"github.com/watchlist-kata/watchlist/internal/tracing"
+	"github.com/watchlist-kata/watchlist/pkg/logger"
+)
+
+// runServe запускает gRPC сервер до отмены контекста.
+// С --dry-run только проверяет конфигурацию, как check-config.
+func runServe(ctx context.Context, e *env, args []string) error {
+	fs, dryRun := e.newFlagSet("serve")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *dryRun {
+		return checkConfig(e, "serve", true)
+	}
+
+	// Загрузка конфигурации
+	cfg, err := e.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// Инициализация кастомного логгера
+	customLogger, err := logger.NewLogger(logger.Options{
+		Brokers:     cfg.KafkaBrokers,
+		KafkaTopic:  cfg.KafkaTopic,
+		ServiceName: cfg.ServiceName,
+		BufferSize:  cfg.LogBufferSize,
+		File:        logFileOptions(cfg),
+		Kafka:       kafkaLogOptions(cfg),
+		KafkaLevel:  cfg.LogLevelKafka,
+		FileLevel:   cfg.LogLevelFile,
+		StdoutLevel: cfg.LogLevelStdout,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+	// Доставляем буферизованные логи последним шагом остановки; то, что не успело уйти
+	// в Kafka за LOG_FLUSH_TIMEOUT, сохраняется в дисковый буфер
+	closeLogger := func() {
+		if multiHandler, ok := logger.MultiHandlerFrom(customLogger); ok {
+			closeCtx, cancel := context.WithTimeout(context.Background(), cfg.LogFlushTimeout)
+			defer cancel()
+			if err := multiHandler.CloseAll(closeCtx); err != nil {
+				fmt.Fprintf(e.stderr, "failed to flush logs: %v\n", err)
+			}
+		}
+	}
+
+	// Инициализация трассировки
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		closeLogger()
+		return withExitCode(ExitConfig, fmt.Errorf("failed to set up tracing: %w", err))
+	}
+
+	// Перезагрузка конфигурации по SIGHUP и при изменении файлов
+	configReloader := config.NewReloader(cfg, e.config.Options(), customLogger)
+	configReloader.Start()
+
+	// Запуск сервера
+	err = server.RunServer(ctx, configReloader, customLogger)
+
+	// Перезагрузка останавливается до закрытия логгера, чтобы не писать в закрытые обработчики
+	configReloader.Stop()
+
+	// Отправляем накопленные спаны
+	tracingCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	if tracingErr := shutdownTracing(tracingCtx); tracingErr != nil {
+		customLogger.Error("failed to shut down tracing", slog.Any("error", tracingErr))
+	}
+	cancel()
+
+	closeLogger()
+	return err
+}
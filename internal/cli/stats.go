@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/watchlist-kata/watchlist/internal/repository"
+)
+
+// statsResult — результат команды stats
+type statsResult struct {
+	Command string `json:"command"`
+	DryRun  bool   `json:"dry_run"`
+	repository.Stats
+}
+
+// runStats выводит число записей, пользователей и медиа в списках просмотра.
+// Команда ничего не изменяет, поэтому --dry-run на нее не влияет.
+func runStats(ctx context.Context, e *env, args []string) error {
+	fs, dryRun := e.newFlagSet("stats")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	s, err := e.openSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	stats, err := s.repo.Stats(ctx)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(e.stdout, statsResult{Command: "stats", DryRun: *dryRun, Stats: stats})
+}
@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/watchlist-kata/watchlist/internal/repository"
+)
+
+// exportItem — запись списка просмотра в выгрузке
+type exportItem struct {
+	MediaID   int64     `json:"media_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// userExport — выгрузка списка просмотра пользователя; import принимает тот же формат
+type userExport struct {
+	UserID int64        `json:"user_id"`
+	Items  []exportItem `json:"items"`
+}
+
+// exportResult — результат команды export-user
+type exportResult struct {
+	Command string `json:"command"`
+	DryRun  bool   `json:"dry_run"`
+	Count   int    `json:"count"`
+	userExport
+}
+
+// runExportUser выводит список просмотра пользователя.
+// С --dry-run выводит только число записей.
+func runExportUser(ctx context.Context, e *env, args []string) error {
+	fs, dryRun := e.newFlagSet("export-user")
+	userID := fs.Int64("user-id", 0, "ID пользователя")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireUserID(*userID); err != nil {
+		return err
+	}
+
+	s, err := e.openSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	items, err := s.repo.GetWatchlist(ctx, uint(*userID))
+	if err != nil {
+		return err
+	}
+
+	result := exportResult{Command: "export-user", DryRun: *dryRun, Count: len(items), userExport: userExport{UserID: *userID}}
+	if !*dryRun {
+		result.Items = make([]exportItem, 0, len(items))
+		for _, item := range items {
+			result.Items = append(result.Items, exportItem{MediaID: int64(item.MediaID), CreatedAt: item.CreatedAt})
+		}
+	}
+	return writeJSON(e.stdout, result)
+}
+
+// importResult — результат команды import
+type importResult struct {
+	Command  string `json:"command"`
+	DryRun   bool   `json:"dry_run"`
+	Users    int    `json:"users"`
+	Imported int    `json:"imported"` // Добавлено (или было бы добавлено) записей
+	Skipped  int    `json:"skipped"`  // Записи, которые уже есть в списке просмотра
+}
+
+// runImport загружает выгрузки export-user из файла или stdin. Во входных данных может быть
+// несколько выгрузок подряд. Все выгрузки проверяются до записи в базу данных.
+// С --dry-run только подсчитывает, сколько записей будет добавлено и пропущено.
+func runImport(ctx context.Context, e *env, args []string) error {
+	fs, dryRun := e.newFlagSet("import")
+	file := fs.String("file", "-", "файл с выгрузкой export-user (- для stdin)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	input := e.stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	exports, err := readExports(input)
+	if err != nil {
+		return withExitCode(ExitUsage, err)
+	}
+
+	s, err := e.openSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	// Повторы внутри входных данных считаются пропущенными и в режиме --dry-run
+	type itemKey struct{ userID, mediaID int64 }
+	seen := make(map[itemKey]bool)
+
+	result := importResult{Command: "import", DryRun: *dryRun, Users: len(exports)}
+	for _, export := range exports {
+		for _, item := range export.Items {
+			key := itemKey{export.UserID, item.MediaID}
+			if seen[key] {
+				result.Skipped++
+				continue
+			}
+			seen[key] = true
+
+			added, err := importItem(ctx, s.repo, export.UserID, item, *dryRun)
+			if err != nil {
+				return err
+			}
+			if added {
+				result.Imported++
+			} else {
+				result.Skipped++
+			}
+		}
+	}
+	return writeJSON(e.stdout, result)
+}
+
+// readExports читает и проверяет все выгрузки из входных данных
+func readExports(r io.Reader) ([]userExport, error) {
+	var exports []userExport
+	decoder := json.NewDecoder(r)
+	for {
+		var export userExport
+		if err := decoder.Decode(&export); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("invalid import data: %w", err)
+		}
+		if export.UserID <= 0 {
+			return nil, fmt.Errorf("invalid import data: user_id must be a positive integer, got %d", export.UserID)
+		}
+		for _, item := range export.Items {
+			if item.MediaID <= 0 {
+				return nil, fmt.Errorf("invalid import data: media_id must be a positive integer, got %d for user ID: %d", item.MediaID, export.UserID)
+			}
+		}
+		exports = append(exports, export)
+	}
+	return exports, nil
+}
+
+// importItem добавляет запись в список просмотра и сообщает, была ли она добавлена.
+// В режиме dryRun только проверяет, есть ли запись в списке.
+func importItem(ctx context.Context, repo *repository.PostgresRepository, userID int64, item exportItem, dryRun bool) (bool, error) {
+	if dryRun {
+		exists, err := repo.CheckInWatchlist(ctx, uint(item.MediaID), uint(userID))
+		return !exists, err
+	}
+
+	err := repo.AddToWatchlist(ctx, &repository.GormWatchlist{
+		MediaID:   uint(item.MediaID),
+		UserID:    uint(userID),
+		CreatedAt: item.CreatedAt,
+	})
+	if errors.Is(err, repository.ErrDuplicateEntry) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// purgeResult — результат команды purge-user
+type purgeResult struct {
+	Command string `json:"command"`
+	DryRun  bool   `json:"dry_run"`
+	UserID  int64  `json:"user_id"`
+	Removed int64  `json:"removed"` // Удалено (или было бы удалено) записей
+}
+
+// runPurgeUser удаляет весь список просмотра пользователя.
+// С --dry-run только подсчитывает записи, которые будут удалены.
+func runPurgeUser(ctx context.Context, e *env, args []string) error {
+	fs, dryRun := e.newFlagSet("purge-user")
+	userID := fs.Int64("user-id", 0, "ID пользователя")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireUserID(*userID); err != nil {
+		return err
+	}
+
+	s, err := e.openSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	result := purgeResult{Command: "purge-user", DryRun: *dryRun, UserID: *userID}
+	if *dryRun {
+		result.Removed, err = s.repo.CountUser(ctx, uint(*userID))
+	} else {
+		result.Removed, err = s.repo.PurgeUser(ctx, uint(*userID))
+	}
+	if err != nil {
+		return err
+	}
+	return writeJSON(e.stdout, result)
+}
@@ -1,74 +1,348 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
-// Config содержит параметры конфигурации приложения
+// RateLimit задает ограничение частоты вызовов метода: пополнение в секунду и емкость корзины
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// LogSampling задает выборку одинаковых сообщений уровня: первые Initial за интервал
+// LOG_SAMPLING_TICK, затем каждое Thereafter-е (0 — остальные отбрасываются)
+type LogSampling struct {
+	Initial    int
+	Thereafter int
+}
+
+// Функции, которые можно отключить без перезапуска через FEATURES
+const (
+	FeatureAccessLog = "access_log" // Строка лога на каждый вызов gRPC
+)
+
+// knownFeatures — поддерживаемые переключатели и их значения по умолчанию
+var knownFeatures = map[string]bool{
+	FeatureAccessLog: true,
+}
+
+// Features — переключатели функций по имени
+type Features map[string]bool
+
+// Enabled сообщает, включена ли функция; незаданные переключатели имеют значение по умолчанию
+func (f Features) Enabled(name string) bool {
+	if enabled, ok := f[name]; ok {
+		return enabled
+	}
+	return knownFeatures[name]
+}
+
+// Config содержит параметры конфигурации приложения.
+// Тег env задает имя переменной окружения; в файле конфигурации используется то же имя
+// в нижнем регистре, во флаге командной строки — в нижнем регистре через дефис.
+// Тег default задает значение по умолчанию, тег secret скрывает значение в выводе Redacted,
+// тег reload отмечает параметры, которые Reloader применяет без перезапуска.
 type Config struct {
-	DBHost        string   // Хост базы данных
-	DBPort        string   // Порт базы данных
-	DBUser        string   // Пользователь базы данных
-	DBPassword    string   // Пароль базы данных
-	DBName        string   // Имя базы данных
-	DBSSLMode     string   // Режим SSL для базы данных
-	KafkaBrokers  []string // Список брокеров Kafka
-	KafkaTopic    string   // Тема Kafka
-	GRPCPort      string   // Порт для gRPC сервиса
-	ServiceName   string   // Имя сервиса
-	LogBufferSize int      // Размер буфера для логов
+	DBHost        string   `env:"DB_HOST" default:"localhost"`            // Хост базы данных
+	DBPort        string   `env:"DB_PORT" default:"5432"`                 // Порт базы данных
+	DBUser        string   `env:"DB_USER" default:"postgres"`             // Пользователь базы данных
+	DBPassword    string   `env:"DB_PASSWORD" secret:"true"`              // Пароль базы данных
+	DBName        string   `env:"DB_NAME" default:"postgres"`             // Имя базы данных
+	DBSSLMode     string   `env:"DB_SSLMODE" default:"prefer"`            // Режим SSL для базы данных
+	KafkaBrokers  []string `env:"KAFKA_BROKERS" default:"localhost:9092"` // Список брокеров Kafka
+	KafkaTopic    string   `env:"KAFKA_TOPIC" default:"watchlist_events"` // Тема Kafka
+	GRPCPort      string   `env:"GRPC_PORT" default:":50054"`             // Порт для gRPC сервиса
+	HTTPPort      string   `env:"HTTP_PORT"`                              // Порт для REST шлюза (пустое значение отключает шлюз)
+	AdminPort     string   `env:"ADMIN_PORT"`                             // Порт административного сервера с метриками (пустое значение отключает сервер)
+	AdminToken    string   `env:"ADMIN_TOKEN" secret:"true"`              // Bearer токен для изменения уровней логов через административный сервер (пустое значение запрещает изменение)
+	ServiceName   string   `env:"SERVICE_NAME" default:"watchlist"`       // Имя сервиса
+	LogBufferSize int      `env:"LOG_BUFFER_SIZE" default:"100"`          // Размер буфера для логов
+	DefaultLocale string   `env:"DEFAULT_LOCALE" default:"ru"`            // Язык сообщений для клиентов по умолчанию
+
+	// Секреты базы данных можно передать файлами (секреты Docker и Kubernetes); файлы перечитываются
+	// при каждом новом соединении пула, поэтому замененные учетные данные применяются без перезапуска
+	DBPasswordFile  string `env:"DB_PASSWORD_FILE"`           // Файл с паролем базы данных вместо DB_PASSWORD
+	DatabaseURL     string `env:"DATABASE_URL" secret:"true"` // Строка подключения целиком (postgres://... или key=value); заменяет параметры DB_*
+	DatabaseURLFile string `env:"DATABASE_URL_FILE"`          // Файл со строкой подключения вместо DATABASE_URL
+
+	// Перехватчики gRPC в порядке вызова
+	GRPCInterceptors []string `env:"GRPC_INTERCEPTORS" default:"request_id,recovery,locale,metrics,access_log,tls_client,auth,rate_limit"`
+
+	DBMaxOpenConns      int           `env:"DB_MAX_OPEN_CONNS" default:"25"`       // Максимальное число открытых соединений с базой данных
+	DBMaxIdleConns      int           `env:"DB_MAX_IDLE_CONNS" default:"10"`       // Максимальное число простаивающих соединений в пуле
+	DBConnMaxLifetime   time.Duration `env:"DB_CONN_MAX_LIFETIME" default:"30m"`   // Максимальное время жизни соединения
+	DBConnMaxIdleTime   time.Duration `env:"DB_CONN_MAX_IDLE_TIME" default:"5m"`   // Максимальное время простоя соединения
+	DBConnectRetries    int           `env:"DB_CONNECT_RETRIES" default:"10"`      // Число попыток подключения к базе данных при старте
+	DBConnectBackoff    time.Duration `env:"DB_CONNECT_BACKOFF" default:"500ms"`   // Начальная задержка между попытками подключения
+	DBConnectMaxBackoff time.Duration `env:"DB_CONNECT_MAX_BACKOFF" default:"30s"` // Максимальная задержка между попытками подключения
+	DBPingInterval      time.Duration `env:"DB_PING_INTERVAL" default:"30s"`       // Интервал фоновой проверки соединения с базой данных
+
+	HealthCheckInterval time.Duration `env:"HEALTH_CHECK_INTERVAL" default:"5s"` // Интервал проверки зависимостей для gRPC health сервиса
+	ShutdownTimeout     time.Duration `env:"SHUTDOWN_TIMEOUT" default:"15s"`     // Максимальное время ожидания завершения запросов при остановке
+
+	TracingExporter     string  `env:"TRACING_EXPORTER" default:"none"`       // Экспортер трассировок: none, stdout, file или otlp
+	TracingFile         string  `env:"TRACING_FILE" default:"traces.json"`    // Файл для экспортера file
+	TracingOTLPEndpoint string  `env:"TRACING_OTLP_ENDPOINT"`                 // Адрес OTLP/gRPC коллектора для экспортера otlp
+	TracingOTLPInsecure bool    `env:"TRACING_OTLP_INSECURE" default:"false"` // Подключаться к OTLP коллектору без TLS
+	TracingSampleRatio  float64 `env:"TRACING_SAMPLE_RATIO" default:"1"`      // Доля трассируемых запросов от 0 до 1
+
+	AuthEnabled       bool          `env:"AUTH_ENABLED" default:"false"`               // Требовать bearer JWT для вызовов WatchlistService
+	AuthJWKSFile      string        `env:"AUTH_JWKS_FILE"`                             // Файл JWKS с ключами проверки подписи
+	AuthStaticKeyFile string        `env:"AUTH_STATIC_KEY_FILE"`                       // Файл со статическим ключом: открытый ключ в PEM или секрет HMAC
+	AuthKeysRefresh   time.Duration `env:"AUTH_KEYS_REFRESH" default:"1m"`             // Интервал проверки JWKS файла на изменения
+	AuthIssuer        string        `env:"AUTH_ISSUER"`                                // Ожидаемый издатель токена
+	AuthAudience      string        `env:"AUTH_AUDIENCE"`                              // Ожидаемая аудитория токена
+	AuthAdminScope    string        `env:"AUTH_ADMIN_SCOPE" default:"watchlist:admin"` // Область доступа, разрешающая работу со списками любых пользователей
+
+	TLSCertFile       string        `env:"TLS_CERT_FILE"`                    // Сертификат gRPC сервера в PEM (пустое значение отключает TLS)
+	TLSKeyFile        string        `env:"TLS_KEY_FILE"`                     // Закрытый ключ gRPC сервера в PEM
+	TLSClientCAFile   string        `env:"TLS_CLIENT_CA_FILE"`               // Сертификаты CA для проверки клиентов (mTLS)
+	TLSClientAuth     string        `env:"TLS_CLIENT_AUTH"`                  // Проверка клиентов: none, request, verify_if_given или require (по умолчанию require, если задан CA)
+	TLSAllowedClients []string      `env:"TLS_ALLOWED_CLIENTS"`              // Разрешённые клиенты по SAN сертификата (пустой список разрешает всех)
+	TLSReloadInterval time.Duration `env:"TLS_RELOAD_INTERVAL" default:"1m"` // Интервал проверки файлов сертификатов на изменения
+
+	RateLimitBackend string               `env:"RATE_LIMIT_BACKEND" default:"memory"` // Хранилище лимитов: memory (в процессе) или postgres (общее для реплик)
+	RateLimitUser    map[string]RateLimit `env:"RATE_LIMIT_USER" reload:"true"`       // Лимиты на пользователя по имени метода
+	RateLimitClient  map[string]RateLimit `env:"RATE_LIMIT_CLIENT" reload:"true"`     // Лимиты на клиента по имени метода
+
+	LogLevel             slog.Level    `env:"LOG_LEVEL" default:"info" reload:"true"`         // Минимальный уровень логов: debug, info, warn или error
+	LogLevelKafka        slog.Level    `env:"LOG_LEVEL_KAFKA" default:"info" reload:"true"`   // Минимальный уровень логов, отправляемых в Kafka
+	LogLevelFile         slog.Level    `env:"LOG_LEVEL_FILE" default:"debug" reload:"true"`   // Минимальный уровень логов в файле
+	LogLevelStdout       slog.Level    `env:"LOG_LEVEL_STDOUT" default:"debug" reload:"true"` // Минимальный уровень логов в stdout
+	Features             Features      `env:"FEATURES" reload:"true"`                         // Переключатели функций, например "access_log=false"
+	ConfigReloadInterval time.Duration `env:"CONFIG_RELOAD_INTERVAL" default:"10s"`           // Интервал проверки файлов конфигурации на изменения (0 — только по SIGHUP)
+
+	LogDir                string        `env:"LOG_DIR" default:"logs"`                 // Каталог файлов логов; файл — <LOG_DIR>/<SERVICE_NAME>/app.log
+	LogFileMaxSize        int           `env:"LOG_FILE_MAX_SIZE" default:"100"`        // Размер файла лога в МБ, после которого он ротируется (0 — без ротации по размеру)
+	LogFileRotateInterval time.Duration `env:"LOG_FILE_ROTATE_INTERVAL" default:"24h"` // Интервал ротации файла лога (0 — без ротации по времени)
+	LogFileMaxBackups     int           `env:"LOG_FILE_MAX_BACKUPS" default:"7"`       // Число хранимых ротированных файлов (0 — без ограничения)
+	LogFileMaxAge         time.Duration `env:"LOG_FILE_MAX_AGE"`                       // Максимальный возраст ротированных файлов (0 — без ограничения)
+	LogFileCompress       bool          `env:"LOG_FILE_COMPRESS" default:"true"`       // Сжимать ротированные файлы gzip
+
+	LogKafkaSpoolDir            string        `env:"LOG_KAFKA_SPOOL_DIR"`                          // Каталог дискового буфера логов Kafka (по умолчанию <LOG_DIR>/<SERVICE_NAME>/kafka-spool)
+	LogKafkaSpoolMaxSize        int           `env:"LOG_KAFKA_SPOOL_MAX_SIZE" default:"256"`       // Максимальный размер дискового буфера в МБ (0 отключает буфер)
+	LogKafkaSpoolReplayInterval time.Duration `env:"LOG_KAFKA_SPOOL_REPLAY_INTERVAL" default:"1s"` // Интервал повторной отправки записей из дискового буфера
+
+	LogKafkaReconnectBackoff    time.Duration `env:"LOG_KAFKA_RECONNECT_BACKOFF" default:"1s"`     // Начальная пауза между попытками подключения к Kafka
+	LogKafkaReconnectMaxBackoff time.Duration `env:"LOG_KAFKA_RECONNECT_MAX_BACKOFF" default:"1m"` // Максимальная пауза между попытками подключения к Kafka
+
+	// Продюсер логов Kafka: подтверждения, сжатие, пакеты, безопасность и маршрутизация
+	LogKafkaAcks          string        `env:"LOG_KAFKA_ACKS" default:"all"`          // Подтверждения записи: all, leader или none
+	LogKafkaRetries       int           `env:"LOG_KAFKA_RETRIES" default:"5"`         // Число повторных попыток доставки
+	LogKafkaCompression   string        `env:"LOG_KAFKA_COMPRESSION" default:"none"`  // Сжатие: none, gzip, snappy, lz4 или zstd
+	LogKafkaLinger        time.Duration `env:"LOG_KAFKA_LINGER"`                      // Время накопления пакета перед отправкой (0 — отправлять сразу)
+	LogKafkaBatchSize     int           `env:"LOG_KAFKA_BATCH_SIZE"`                  // Размер пакета в байтах, при котором он отправляется (0 — по умолчанию sarama)
+	LogKafkaBatchMessages int           `env:"LOG_KAFKA_BATCH_MESSAGES"`              // Число сообщений в пакете, при котором он отправляется (0 — по умолчанию sarama)
+	LogKafkaTLS           bool          `env:"LOG_KAFKA_TLS" default:"false"`         // Подключаться к брокерам по TLS
+	LogKafkaTLSCAFile     string        `env:"LOG_KAFKA_TLS_CA_FILE"`                 // Сертификаты CA для проверки брокеров в PEM (пустое значение — системные)
+	LogKafkaTLSCertFile   string        `env:"LOG_KAFKA_TLS_CERT_FILE"`               // Клиентский сертификат в PEM для mTLS
+	LogKafkaTLSKeyFile    string        `env:"LOG_KAFKA_TLS_KEY_FILE"`                // Закрытый ключ клиентского сертификата в PEM
+	LogKafkaSASLMechanism string        `env:"LOG_KAFKA_SASL_MECHANISM"`              // Механизм SASL: PLAIN, SCRAM-SHA-256 или SCRAM-SHA-512 (пустое значение отключает SASL)
+	LogKafkaSASLUsername  string        `env:"LOG_KAFKA_SASL_USERNAME"`               // Имя пользователя SASL
+	LogKafkaSASLPassword  string        `env:"LOG_KAFKA_SASL_PASSWORD" secret:"true"` // Пароль SASL
+	LogKafkaErrorTopic    string        `env:"LOG_KAFKA_ERROR_TOPIC"`                 // Тема для записей уровня error (пустое значение — общая тема KAFKA_TOPIC)
+
+	LogFlushTimeout time.Duration `env:"LOG_FLUSH_TIMEOUT" default:"5s"` // Максимальное время доставки буферизованных логов при остановке
+
+	// Поведение асинхронных обработчиков (Kafka и файл) при переполнении буфера, выборка и схлопывание повторов
+	LogKafkaOverflow     string                     `env:"LOG_KAFKA_OVERFLOW" default:"drop_newest"` // Политика переполнения буфера Kafka: drop_newest, drop_oldest, block_timeout или block
+	LogKafkaBlockTimeout time.Duration              `env:"LOG_KAFKA_BLOCK_TIMEOUT" default:"100ms"`  // Время ожидания места в буфере Kafka для block_timeout
+	LogFileOverflow      string                     `env:"LOG_FILE_OVERFLOW" default:"drop_newest"`  // Политика переполнения буфера файла
+	LogFileBlockTimeout  time.Duration              `env:"LOG_FILE_BLOCK_TIMEOUT" default:"100ms"`   // Время ожидания места в буфере файла для block_timeout
+	LogSampling          map[slog.Level]LogSampling `env:"LOG_SAMPLING"`                             // Выборка по уровням, например "debug=10:100,info=100:10" (пустое значение отключает)
+	LogSamplingTick      time.Duration              `env:"LOG_SAMPLING_TICK" default:"1s"`           // Интервал, за который считаются одинаковые сообщения
+	LogDedupWindow       time.Duration              `env:"LOG_DEDUP_WINDOW"`                         // Окно схлопывания подряд идущих одинаковых записей в "repeated N times" (0 отключает)
+
+	sources map[string]string // Источник значения каждого параметра, для Redacted
+}
+
+// Options задает источники конфигурации помимо значений по умолчанию и окружения
+type Options struct {
+	EnvFile string            // Файл .env; если его нет, используется только окружение (по умолчанию ".env")
+	File    string            // Файл конфигурации YAML или TOML (по умолчанию из CONFIG_FILE, если задан)
+	Flags   map[string]string // Значения из флагов командной строки по имени переменной окружения
 }
 
-// LoadConfig загружает конфигурацию из .env файла
+// envFile возвращает путь к файлу .env
+func (o Options) envFile() string {
+	if o.EnvFile != "" {
+		return o.EnvFile
+	}
+	return ".env"
+}
+
+// configFile возвращает путь к файлу конфигурации: из опций, окружения или файла .env
+func (o Options) configFile(dotenv map[string]string) string {
+	if o.File != "" {
+		return o.File
+	}
+	if file := os.Getenv("CONFIG_FILE"); file != "" {
+		return file
+	}
+	return dotenv["CONFIG_FILE"]
+}
+
+// readEnvFile читает параметры из файла .env, не изменяя окружение процесса, чтобы
+// при повторной загрузке изменения в файле были видны. Помимо параметров сохраняется
+// CONFIG_FILE, чтобы путь к файлу конфигурации можно было задать в .env. Файл необязателен:
+// в Kubernetes все переменные обычно уже заданы в окружении.
+func readEnvFile(path string) (map[string]string, error) {
+	values, err := godotenv.Read(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s file: %w", path, err)
+	}
+
+	known := make(map[string]string, len(params)+1)
+	for _, p := range params {
+		if value, ok := values[p.key]; ok {
+			known[p.key] = value
+		}
+	}
+	if file, ok := values["CONFIG_FILE"]; ok {
+		known["CONFIG_FILE"] = file
+	}
+	return known, nil
+}
+
+// LoadConfig загружает конфигурацию из окружения, файла .env и файла CONFIG_FILE, если они есть
 func LoadConfig() (*Config, error) {
-	// Загружаем переменные окружения из .env файла
-	err := godotenv.Load()
+	return Load(Options{})
+}
+
+// Load собирает конфигурацию по слоям: значения по умолчанию, файл конфигурации, файл .env,
+// окружение и флаги командной строки. Каждый следующий слой переопределяет предыдущий.
+// Все ошибки разбора и проверки возвращаются вместе, объединенные errors.Join.
+func Load(opts Options) (*Config, error) {
+	dotenv, err := readEnvFile(opts.envFile())
 	if err != nil {
-		return nil, fmt.Errorf("failed to load .env file: %w", err)
+		return nil, err
 	}
 
-	// Проверяем обязательные переменные окружения
-	requiredEnvVars := []string{
-		"DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD",
-		"DB_NAME", "DB_SSLMODE", "KAFKA_BROKERS", "KAFKA_TOPIC",
-		"GRPC_PORT", "SERVICE_NAME", "LOG_BUFFER_SIZE",
+	values, sources := defaultValues()
+
+	if file := opts.configFile(dotenv); file != "" {
+		fileValues, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		merge(values, sources, fileValues, "file")
 	}
 
-	for _, envVar := range requiredEnvVars {
-		if value := os.Getenv(envVar); value == "" {
-			return nil, fmt.Errorf("missing required environment variable: %s", envVar)
+	merge(values, sources, dotenv, "env_file")
+	merge(values, sources, envValues(), "env")
+	merge(values, sources, opts.Flags, "flag")
+
+	cfg := &Config{sources: sources}
+	errs := cfg.set(values)
+
+	// TLS_CLIENT_AUTH по умолчанию зависит от того, задан ли CA для проверки клиентов
+	if cfg.TLSClientAuth == "" {
+		cfg.TLSClientAuth = "none"
+		if cfg.TLSClientCAFile != "" {
+			cfg.TLSClientAuth = "require"
 		}
 	}
 
-	// Преобразуем KAFKA_BROKERS в []string
-	kafkaBrokers := strings.Split(os.Getenv("KAFKA_BROKERS"), ",")
-	if len(kafkaBrokers) == 0 || (len(kafkaBrokers) == 1 && kafkaBrokers[0] == "") {
-		return nil, fmt.Errorf("invalid KAFKA_BROKERS value")
-	}
-
-	// Преобразуем LOG_BUFFER_SIZE в int с дефолтным значением 100, если не задано корректно
-	logBufferSize, err := strconv.Atoi(os.Getenv("LOG_BUFFER_SIZE"))
-	if err != nil || logBufferSize <= 0 {
-		logBufferSize = 100 // Значение по умолчанию
-	}
-
-	// Возвращаем конфигурацию
-	return &Config{
-		DBHost:        os.Getenv("DB_HOST"),
-		DBPort:        os.Getenv("DB_PORT"),
-		DBUser:        os.Getenv("DB_USER"),
-		DBPassword:    os.Getenv("DB_PASSWORD"),
-		DBName:        os.Getenv("DB_NAME"),
-		DBSSLMode:     os.Getenv("DB_SSLMODE"),
-		KafkaBrokers:  kafkaBrokers,
-		KafkaTopic:    os.Getenv("KAFKA_TOPIC"),
-		GRPCPort:      os.Getenv("GRPC_PORT"),
-		ServiceName:   os.Getenv("SERVICE_NAME"),
-		LogBufferSize: logBufferSize,
-	}, nil
+	// Параметры, которые не удалось разобрать, повторно не проверяются
+	for _, err := range cfg.validate() {
+		if !slices.ContainsFunc(errs, func(parseErr error) bool {
+			key, _, _ := strings.Cut(parseErr.Error(), ":")
+			return strings.HasPrefix(err.Error(), key+":")
+		}) {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// validate проверяет значения параметров и их сочетания
+func (c *Config) validate() []error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	for key, value := range map[string]string{
+		"DB_HOST": c.DBHost, "DB_PORT": c.DBPort, "DB_USER": c.DBUser, "DB_NAME": c.DBName,
+		"KAFKA_TOPIC": c.KafkaTopic, "GRPC_PORT": c.GRPCPort, "SERVICE_NAME": c.ServiceName,
+	} {
+		check(value != "", key, "must not be empty")
+	}
+	check(len(c.KafkaBrokers) > 0, "KAFKA_BROKERS", "must not be empty")
+
+	check(c.DBPassword == "" || c.DBPasswordFile == "", "DB_PASSWORD_FILE", "must not be set together with DB_PASSWORD")
+	check(c.DatabaseURL == "" || c.DatabaseURLFile == "", "DATABASE_URL_FILE", "must not be set together with DATABASE_URL")
+
+	if port, err := strconv.Atoi(c.DBPort); c.DBPort != "" && (err != nil || port < 1 || port > 65535) {
+		errs = append(errs, fmt.Errorf("DB_PORT: invalid port %q", c.DBPort))
+	}
+	check(slices.Contains([]string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}, c.DBSSLMode),
+		"DB_SSLMODE", "unsupported mode %q", c.DBSSLMode)
+	check(c.LogBufferSize > 0, "LOG_BUFFER_SIZE", "must be positive")
+	check(c.LogDir != "", "LOG_DIR", "must not be empty")
+	check(c.LogFlushTimeout > 0, "LOG_FLUSH_TIMEOUT", "must be positive")
+	check(slices.Contains([]string{"all", "leader", "none"}, c.LogKafkaAcks), "LOG_KAFKA_ACKS", "unsupported value %q", c.LogKafkaAcks)
+	check(c.LogKafkaRetries >= 0, "LOG_KAFKA_RETRIES", "must not be negative")
+	check(slices.Contains([]string{"none", "gzip", "snappy", "lz4", "zstd"}, c.LogKafkaCompression),
+		"LOG_KAFKA_COMPRESSION", "unsupported codec %q", c.LogKafkaCompression)
+	check(c.LogKafkaBatchSize >= 0, "LOG_KAFKA_BATCH_SIZE", "must not be negative")
+	check(c.LogKafkaBatchMessages >= 0, "LOG_KAFKA_BATCH_MESSAGES", "must not be negative")
+	check((c.LogKafkaTLSCertFile == "") == (c.LogKafkaTLSKeyFile == ""), "LOG_KAFKA_TLS_CERT_FILE",
+		"LOG_KAFKA_TLS_CERT_FILE and LOG_KAFKA_TLS_KEY_FILE must be set together")
+	check(slices.Contains([]string{"", "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512"}, c.LogKafkaSASLMechanism),
+		"LOG_KAFKA_SASL_MECHANISM", "unsupported mechanism %q", c.LogKafkaSASLMechanism)
+	check(c.LogKafkaSASLMechanism == "" || c.LogKafkaSASLUsername != "", "LOG_KAFKA_SASL_USERNAME", "is required with LOG_KAFKA_SASL_MECHANISM")
+	overflowPolicies := []string{"drop_newest", "drop_oldest", "block_timeout", "block"}
+	check(slices.Contains(overflowPolicies, c.LogKafkaOverflow), "LOG_KAFKA_OVERFLOW", "unsupported policy %q", c.LogKafkaOverflow)
+	check(slices.Contains(overflowPolicies, c.LogFileOverflow), "LOG_FILE_OVERFLOW", "unsupported policy %q", c.LogFileOverflow)
+	check(c.LogKafkaOverflow != "block_timeout" || c.LogKafkaBlockTimeout > 0, "LOG_KAFKA_BLOCK_TIMEOUT", "must be positive for block_timeout")
+	check(c.LogFileOverflow != "block_timeout" || c.LogFileBlockTimeout > 0, "LOG_FILE_BLOCK_TIMEOUT", "must be positive for block_timeout")
+	check(len(c.LogSampling) == 0 || c.LogSamplingTick > 0, "LOG_SAMPLING_TICK", "must be positive when LOG_SAMPLING is set")
+	check(c.HealthCheckInterval > 0, "HEALTH_CHECK_INTERVAL", "must be positive")
+	check(c.DBConnectMaxBackoff >= c.DBConnectBackoff, "DB_CONNECT_MAX_BACKOFF", "must not be less than DB_CONNECT_BACKOFF")
+
+	check(slices.Contains([]string{"none", "stdout", "file", "otlp"}, c.TracingExporter),
+		"TRACING_EXPORTER", "unsupported exporter %q", c.TracingExporter)
+	check(c.TracingSampleRatio <= 1, "TRACING_SAMPLE_RATIO", "must be between 0 and 1")
+
+	check(!c.AuthEnabled || c.AuthJWKSFile != "" || c.AuthStaticKeyFile != "",
+		"AUTH_ENABLED", "AUTH_JWKS_FILE or AUTH_STATIC_KEY_FILE is required")
+
+	check((c.TLSCertFile == "") == (c.TLSKeyFile == ""), "TLS_CERT_FILE", "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	check(slices.Contains([]string{"none", "request", "verify_if_given", "require"}, c.TLSClientAuth),
+		"TLS_CLIENT_AUTH", "unsupported mode %q", c.TLSClientAuth)
+	// REST шлюз принимает запросы без TLS, поэтому проверка клиентов по сертификату для него невозможна
+	check(c.HTTPPort == "" || c.TLSCertFile == "" || (c.TLSClientAuth == "none" && len(c.TLSAllowedClients) == 0), "HTTP_PORT",
+		"REST gateway cannot be enabled together with TLS client verification (TLS_CLIENT_AUTH, TLS_ALLOWED_CLIENTS)")
+
+	check(c.RateLimitBackend == "memory" || c.RateLimitBackend == "postgres",
+		"RATE_LIMIT_BACKEND", "unsupported backend %q", c.RateLimitBackend)
+
+	for name := range c.Features {
+		_, ok := knownFeatures[name]
+		check(ok, "FEATURES", "unknown feature %q", name)
+	}
+
+	// Порядок ошибок не должен зависеть от обхода map
+	slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return errs
 }
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFile создает файл с содержимым content во временном каталоге теста
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadConfigFileFromEnvFile(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	configFile := writeFile(t, "config.yaml", `
+db_user: watchlist
+db_name: watchlist
+kafka_brokers: [localhost:9092]
+kafka_topic: logs
+grpc_port: ":50051"
+service_name: watchlist-from-file
+`)
+	envFile := writeFile(t, ".env", "CONFIG_FILE="+configFile+"\n")
+
+	cfg, err := Load(Options{EnvFile: envFile})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ServiceName != "watchlist-from-file" {
+		t.Errorf("ServiceName = %q, want value from CONFIG_FILE set in .env", cfg.ServiceName)
+	}
+
+	reloader := NewReloader(cfg, Options{EnvFile: envFile}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if _, ok := reloader.fileModTimes()[configFile]; !ok {
+		t.Errorf("Reloader does not watch CONFIG_FILE set in .env")
+	}
+}
+
+func TestRedactedFormatsLogSampling(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("LOG_SAMPLING", "info=100:10,debug=10:100")
+
+	envFile := writeFile(t, ".env", "DB_USER=watchlist\nDB_NAME=watchlist\nKAFKA_BROKERS=localhost:9092\nKAFKA_TOPIC=logs\nGRPC_PORT=:50051\n")
+	cfg, err := Load(Options{EnvFile: envFile})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, setting := range cfg.Redacted() {
+		if setting.Key == "LOG_SAMPLING" && setting.Value != "debug=10:100,info=100:10" {
+			t.Errorf("LOG_SAMPLING = %q, want the format it is set in", setting.Value)
+		}
+	}
+}
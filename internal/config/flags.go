@@ -0,0 +1,36 @@
+package config
+
+import "flag"
+
+// Flags связывает параметры конфигурации с флагами командной строки
+type Flags struct {
+	fs   *flag.FlagSet
+	file *string
+	keys map[string]string // Имя параметра по имени флага
+}
+
+// RegisterFlags добавляет в набор флагов --config и по флагу на каждый параметр конфигурации,
+// например --db-host для DB_HOST
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{
+		fs:   fs,
+		file: fs.String("config", "", "файл конфигурации YAML или TOML (по умолчанию CONFIG_FILE)"),
+		keys: make(map[string]string, len(params)),
+	}
+	for _, p := range params {
+		fs.String(p.flagName(), "", "переопределяет "+p.key)
+		f.keys[p.flagName()] = p.key
+	}
+	return f
+}
+
+// Options возвращает источники конфигурации из флагов, явно заданных в командной строке
+func (f *Flags) Options() Options {
+	opts := Options{File: *f.file, Flags: make(map[string]string)}
+	f.fs.Visit(func(fl *flag.Flag) {
+		if key, ok := f.keys[fl.Name]; ok {
+			opts.Flags[key] = fl.Value.String()
+		}
+	})
+	return opts
+}
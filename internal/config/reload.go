@@ -0,0 +1,186 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Subscriber получает конфигурацию после применения изменений в параметрах с тегом reload
+type Subscriber func(cfg *Config)
+
+// Reloader перечитывает конфигурацию по SIGHUP или при изменении файлов .env и CONFIG_FILE.
+// Без перезапуска применяются только параметры с тегом reload; об изменениях остальных
+// параметров Reloader предупреждает в логах. Конфигурация с ошибками отклоняется целиком,
+// и продолжают действовать прежние значения.
+type Reloader struct {
+	opts   Options
+	logger *slog.Logger
+
+	mu          sync.Mutex
+	current     *Config
+	subscribers []Subscriber
+	modTimes    map[string]time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewReloader создает Reloader для конфигурации cfg, загруженной с опциями opts
+func NewReloader(cfg *Config, opts Options, logger *slog.Logger) *Reloader {
+	r := &Reloader{
+		opts:    opts,
+		logger:  logger,
+		current: cfg,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	r.modTimes = r.fileModTimes()
+	return r
+}
+
+// Current возвращает действующую конфигурацию
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Subscribe добавляет подписчика на изменения конфигурации
+func (r *Reloader) Subscribe(subscriber Subscriber) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribers = append(r.subscribers, subscriber)
+}
+
+// Start запускает ожидание SIGHUP и проверку файлов с интервалом CONFIG_RELOAD_INTERVAL
+func (r *Reloader) Start() {
+	go r.run()
+}
+
+// Stop останавливает Reloader и дожидается завершения фоновой горутины
+func (r *Reloader) Stop() {
+	close(r.stop)
+	<-r.done
+}
+
+// run перечитывает конфигурацию по сигналу или изменению файлов
+func (r *Reloader) run() {
+	defer close(r.done)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if interval := r.Current().ConfigReloadInterval; interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-hup:
+			r.logger.Info("SIGHUP received, reloading configuration")
+			r.Reload()
+		case <-tick:
+			if r.filesChanged() {
+				r.logger.Info("configuration files changed, reloading configuration")
+				r.Reload()
+			}
+		}
+	}
+}
+
+// Reload перечитывает конфигурацию и применяет изменения параметров с тегом reload.
+// Возвращает ошибку, если новая конфигурация отклонена.
+func (r *Reloader) Reload() error {
+	modTimes := r.fileModTimes()
+	r.mu.Lock()
+	r.modTimes = modTimes
+	r.mu.Unlock()
+
+	loaded, err := Load(r.opts)
+	if err != nil {
+		r.logger.Error("configuration reload rejected, keeping previous values", slog.Any("error", err))
+		return fmt.Errorf("configuration reload rejected: %w", err)
+	}
+
+	r.mu.Lock()
+	next, changed, restart := r.current.withReloadable(loaded)
+	if len(restart) > 0 {
+		r.logger.Warn(fmt.Sprintf("configuration parameters changed but require a restart: %s", strings.Join(restart, ", ")))
+	}
+	if len(changed) == 0 {
+		r.mu.Unlock()
+		r.logger.Info("configuration reloaded, no changes to apply")
+		return nil
+	}
+	r.current = next
+	subscribers := append([]Subscriber(nil), r.subscribers...)
+	r.mu.Unlock()
+
+	for _, subscriber := range subscribers {
+		subscriber(next)
+	}
+	r.logger.Info(fmt.Sprintf("configuration reloaded, applied: %s", strings.Join(changed, ", ")))
+	return nil
+}
+
+// withReloadable возвращает копию конфигурации с параметрами с тегом reload из loaded,
+// список примененных параметров и список измененных параметров, требующих перезапуска
+func (c *Config) withReloadable(loaded *Config) (*Config, []string, []string) {
+	next := *c
+	next.sources = make(map[string]string, len(c.sources))
+	for key, source := range c.sources {
+		next.sources[key] = source
+	}
+
+	current, nextValue, loadedValue := reflect.ValueOf(c).Elem(), reflect.ValueOf(&next).Elem(), reflect.ValueOf(loaded).Elem()
+	var changed, restart []string
+	for _, p := range params {
+		if reflect.DeepEqual(current.Field(p.index).Interface(), loadedValue.Field(p.index).Interface()) {
+			continue
+		}
+		if !p.reload {
+			restart = append(restart, p.key)
+			continue
+		}
+		nextValue.Field(p.index).Set(loadedValue.Field(p.index))
+		next.sources[p.key] = loaded.sources[p.key]
+		changed = append(changed, p.key)
+	}
+	return &next, changed, restart
+}
+
+// filesChanged сообщает, изменились ли файлы конфигурации с последней загрузки
+func (r *Reloader) filesChanged() bool {
+	modTimes := r.fileModTimes()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !reflect.DeepEqual(modTimes, r.modTimes)
+}
+
+// fileModTimes возвращает время изменения файлов конфигурации
+func (r *Reloader) fileModTimes() map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	dotenv, _ := readEnvFile(r.opts.envFile())
+	for _, path := range []string{r.opts.envFile(), r.opts.configFile(dotenv)} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			modTimes[path] = info.ModTime()
+		}
+	}
+	return modTimes
+}
@@ -0,0 +1,393 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// redacted заменяет значения секретных параметров в выводе Redacted
+const redacted = "******"
+
+// param описывает параметр конфигурации, заданный тегами поля Config
+type param struct {
+	key    string // Имя переменной окружения
+	def    string // Значение по умолчанию
+	secret bool   // Значение скрывается в выводе Redacted
+	reload bool   // Значение применяется без перезапуска
+	index  int    // Индекс поля в Config
+}
+
+// params — параметры конфигурации в порядке объявления полей Config
+var params = func() []param {
+	t := reflect.TypeOf(Config{})
+	var list []param
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, ok := field.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		list = append(list, param{
+			key:    key,
+			def:    field.Tag.Get("default"),
+			secret: field.Tag.Get("secret") == "true",
+			reload: field.Tag.Get("reload") == "true",
+			index:  i,
+		})
+	}
+	return list
+}()
+
+// fileKey возвращает имя параметра в файле конфигурации
+func (p param) fileKey() string {
+	return strings.ToLower(p.key)
+}
+
+// flagName возвращает имя флага командной строки для параметра
+func (p param) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(p.key), "_", "-")
+}
+
+// defaultValues возвращает значения по умолчанию и их источник
+func defaultValues() (map[string]string, map[string]string) {
+	values := make(map[string]string, len(params))
+	sources := make(map[string]string, len(params))
+	for _, p := range params {
+		values[p.key] = p.def
+		sources[p.key] = "default"
+	}
+	return values, sources
+}
+
+// envValues возвращает параметры, заданные в окружении (в том числе пустыми значениями)
+func envValues() map[string]string {
+	values := make(map[string]string)
+	for _, p := range params {
+		if value, ok := os.LookupEnv(p.key); ok {
+			values[p.key] = value
+		}
+	}
+	return values
+}
+
+// merge переносит значения слоя source поверх уже собранных
+func merge(values, sources, layer map[string]string, source string) {
+	for key, value := range layer {
+		values[key] = value
+		sources[key] = source
+	}
+}
+
+// readFile читает файл конфигурации YAML (.yaml, .yml) или TOML (.toml).
+// Ключи — имена переменных окружения в нижнем регистре, например db_host.
+// Списки задаются массивами, лимиты — таблицами вида {AddToWatchlist = "5:10"}.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	raw := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q: use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	keys := make(map[string]string, len(params))
+	for _, p := range params {
+		keys[p.fileKey()] = p.key
+	}
+
+	values := make(map[string]string, len(raw))
+	var unknown []string
+	for name, value := range raw {
+		key, ok := keys[strings.ToLower(name)]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		values[key] = fileValue(value)
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown parameters in config file %s: %s", path, strings.Join(unknown, ", "))
+	}
+	return values, nil
+}
+
+// fileValue приводит значение из файла к текстовому виду, как в переменной окружения
+func fileValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, fileValue(item))
+		}
+		return strings.Join(items, ",")
+	case map[string]interface{}:
+		items := make([]string, 0, len(v))
+		for name, item := range v {
+			items = append(items, name+"="+fileValue(item))
+		}
+		sort.Strings(items)
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// set разбирает собранные значения в поля Config и возвращает ошибки всех неверных параметров
+func (c *Config) set(values map[string]string) []error {
+	v := reflect.ValueOf(c).Elem()
+	var errs []error
+	for _, p := range params {
+		if err := setField(v.Field(p.index), values[p.key]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q: %w", p.key, values[p.key], err))
+		}
+	}
+	return errs
+}
+
+// setField разбирает значение параметра по типу поля
+func setField(field reflect.Value, value string) error {
+	value = strings.TrimSpace(value)
+
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(value)
+	case int:
+		if value == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		if n < 0 {
+			return fmt.Errorf("must not be negative")
+		}
+		field.SetInt(int64(n))
+	case bool:
+		if value == "" {
+			return nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("must be true or false")
+		}
+		field.SetBool(b)
+	case float64:
+		if value == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		if f < 0 {
+			return fmt.Errorf("must not be negative")
+		}
+		field.SetFloat(f)
+	case time.Duration:
+		if value == "" {
+			return nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("must be a duration such as 5s or 1m30s")
+		}
+		if d < 0 {
+			return fmt.Errorf("must not be negative")
+		}
+		field.SetInt(int64(d))
+	case slog.Level:
+		if value == "" {
+			return nil
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("must be debug, info, warn or error")
+		}
+		field.Set(reflect.ValueOf(level))
+	case []string:
+		field.Set(reflect.ValueOf(parseList(value)))
+	case Features:
+		features, err := parseFeatures(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(features))
+	case map[string]RateLimit:
+		limits, err := parseRateLimits(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(limits))
+	case map[slog.Level]LogSampling:
+		sampling, err := parseLogSampling(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(sampling))
+	default:
+		return fmt.Errorf("unsupported parameter type %s", field.Type())
+	}
+	return nil
+}
+
+// parseList разбирает список значений через запятую, пропуская пустые элементы
+func parseList(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// parseFeatures разбирает переключатели в формате "имя=true|false,...";
+// имя без значения включает функцию
+func parseFeatures(value string) (Features, error) {
+	features := make(Features)
+	for _, item := range parseList(value) {
+		name, enabledValue, ok := strings.Cut(item, "=")
+		enabled := true
+		if ok {
+			var err error
+			if enabled, err = strconv.ParseBool(strings.TrimSpace(enabledValue)); err != nil {
+				return nil, fmt.Errorf("%q must be name=true or name=false", item)
+			}
+		}
+		features[strings.TrimSpace(name)] = enabled
+	}
+	return features, nil
+}
+
+// parseRateLimits разбирает лимиты в формате "Метод=пополнение_в_секунду:емкость,...",
+// например "AddToWatchlist=5:10,CheckInWatchlist=20:40"
+func parseRateLimits(value string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit)
+	for _, item := range parseList(value) {
+		method, spec, ok := strings.Cut(item, "=")
+		rateValue, burstValue, ok2 := strings.Cut(spec, ":")
+		if !ok || !ok2 || method == "" {
+			return nil, fmt.Errorf("%q must be Method=rate:burst", item)
+		}
+		rate, err := strconv.ParseFloat(rateValue, 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("rate for %s must be a positive number", method)
+		}
+		burst, err := strconv.Atoi(burstValue)
+		if err != nil || burst < 1 {
+			return nil, fmt.Errorf("burst for %s must be a positive integer", method)
+		}
+		limits[method] = RateLimit{Rate: rate, Burst: burst}
+	}
+	return limits, nil
+}
+
+// parseLogSampling разбирает выборку в формате "уровень=первые:каждое_N-е,...",
+// например "debug=10:100,info=100:10"
+func parseLogSampling(value string) (map[slog.Level]LogSampling, error) {
+	sampling := make(map[slog.Level]LogSampling)
+	for _, item := range parseList(value) {
+		levelValue, spec, ok := strings.Cut(item, "=")
+		initialValue, thereafterValue, ok2 := strings.Cut(spec, ":")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("%q must be level=initial:thereafter", item)
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(levelValue))); err != nil {
+			return nil, fmt.Errorf("level in %q must be debug, info, warn or error", item)
+		}
+		initial, err := strconv.Atoi(initialValue)
+		if err != nil || initial < 0 {
+			return nil, fmt.Errorf("initial for %s must be a non-negative integer", levelValue)
+		}
+		thereafter, err := strconv.Atoi(thereafterValue)
+		if err != nil || thereafter < 0 {
+			return nil, fmt.Errorf("thereafter for %s must be a non-negative integer", levelValue)
+		}
+		sampling[level] = LogSampling{Initial: initial, Thereafter: thereafter}
+	}
+	return sampling, nil
+}
+
+// Setting — параметр действующей конфигурации и источник его значения
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"` // default, file, env_file, env или flag
+}
+
+// Redacted возвращает действующие значения всех параметров в порядке объявления.
+// Значения секретных параметров скрыты.
+func (c *Config) Redacted() []Setting {
+	v := reflect.ValueOf(c).Elem()
+	settings := make([]Setting, 0, len(params))
+	for _, p := range params {
+		value := formatField(v.Field(p.index))
+		if p.secret && value != "" {
+			value = redacted
+		}
+		source := c.sources[p.key]
+		if source == "" {
+			source = "default"
+		}
+		settings = append(settings, Setting{Key: p.key, Value: value, Source: source})
+	}
+	return settings
+}
+
+// formatField возвращает значение поля в том же текстовом виде, в котором оно задается
+func formatField(field reflect.Value) string {
+	switch v := field.Interface().(type) {
+	case []string:
+		return strings.Join(v, ",")
+	case slog.Level:
+		return strings.ToLower(v.String())
+	case Features:
+		items := make([]string, 0, len(v))
+		for name, enabled := range v {
+			items = append(items, name+"="+strconv.FormatBool(enabled))
+		}
+		sort.Strings(items)
+		return strings.Join(items, ",")
+	case map[string]RateLimit:
+		items := make([]string, 0, len(v))
+		for method, limit := range v {
+			items = append(items, fmt.Sprintf("%s=%s:%d", method, strconv.FormatFloat(limit.Rate, 'f', -1, 64), limit.Burst))
+		}
+		sort.Strings(items)
+		return strings.Join(items, ",")
+	case map[slog.Level]LogSampling:
+		items := make([]string, 0, len(v))
+		for level, sampling := range v {
+			items = append(items, fmt.Sprintf("%s=%d:%d", strings.ToLower(level.String()), sampling.Initial, sampling.Thereafter))
+		}
+		sort.Strings(items)
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,161 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Check проверяет одну зависимость сервиса и возвращает ошибку, если она недоступна
+type Check func(ctx context.Context) error
+
+// service описывает сервис, статус которого публикуется через gRPC health
+type service struct {
+	name     string
+	critical bool
+	checks   []Check
+	status   healthpb.HealthCheckResponse_ServingStatus
+}
+
+// Checker периодически выполняет проверки зависимостей и публикует статусы в gRPC health сервер.
+// Общий статус (пустое имя сервиса) равен SERVING, только если все критичные сервисы доступны.
+type Checker struct {
+	server   *health.Server
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	services []*service
+	shutdown bool
+
+	quitChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewChecker создает новый экземпляр Checker. До первой проверки все сервисы имеют статус NOT_SERVING.
+func NewChecker(interval time.Duration, logger *slog.Logger) *Checker {
+	server := health.NewServer()
+	server.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	return &Checker{
+		server:   server,
+		interval: interval,
+		logger:   logger,
+		quitChan: make(chan struct{}),
+	}
+}
+
+// Server возвращает gRPC health сервер для регистрации
+func (c *Checker) Server() healthpb.HealthServer {
+	return c.server
+}
+
+// AddService регистрирует сервис с набором проверок. Недоступность критичного сервиса
+// переводит общий статус в NOT_SERVING.
+func (c *Checker) AddService(name string, critical bool, checks ...Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.services = append(c.services, &service{
+		name:     name,
+		critical: critical,
+		checks:   checks,
+		status:   healthpb.HealthCheckResponse_NOT_SERVING,
+	})
+	c.server.SetServingStatus(name, healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+// Start выполняет первую проверку и запускает периодические проверки в фоне
+func (c *Checker) Start() {
+	c.checkAll()
+
+	c.wg.Add(1)
+	go c.run()
+}
+
+// run выполняет проверки с заданным интервалом до остановки
+func (c *Checker) run() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.checkAll()
+		case <-c.quitChan:
+			return
+		}
+	}
+}
+
+// checkAll выполняет проверки всех сервисов и обновляет их статусы
+func (c *Checker) checkAll() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.interval)
+	defer cancel()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.shutdown {
+		return
+	}
+
+	overall := healthpb.HealthCheckResponse_SERVING
+	for _, svc := range c.services {
+		status := healthpb.HealthCheckResponse_SERVING
+		if err := runChecks(ctx, svc.checks); err != nil {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+			if svc.status != status {
+				c.logger.Warn(fmt.Sprintf("health status of %s changed to %s", svc.name, status), slog.Any("error", err))
+			}
+		} else if svc.status != status {
+			c.logger.Info(fmt.Sprintf("health status of %s changed to %s", svc.name, status))
+		}
+
+		svc.status = status
+		c.server.SetServingStatus(svc.name, status)
+
+		if svc.critical && status != healthpb.HealthCheckResponse_SERVING {
+			overall = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+	}
+	c.server.SetServingStatus("", overall)
+}
+
+// runChecks выполняет проверки и объединяет их ошибки
+func runChecks(ctx context.Context, checks []Check) error {
+	var errs []error
+	for _, check := range checks {
+		if err := check(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown переводит все сервисы в NOT_SERVING и больше не меняет их статусы.
+// Вызывается в начале остановки сервера, чтобы балансировщики перестали направлять запросы.
+func (c *Checker) Shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.shutdown {
+		return
+	}
+	c.shutdown = true
+	c.server.Shutdown()
+}
+
+// Stop останавливает периодические проверки и дожидается их завершения
+func (c *Checker) Stop() {
+	close(c.quitChan)
+	c.wg.Wait()
+}
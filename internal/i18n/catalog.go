@@ -0,0 +1,69 @@
+package i18n
+
+import "golang.org/x/text/language"
+
+// Key — идентификатор сообщения в каталоге
+type Key string
+
+// Сообщения для клиентов
+const (
+	KeyInvalidIDs       Key = "invalid_ids"
+	KeyInvalidUserID    Key = "invalid_user_id"
+	KeyMustBePositive   Key = "must_be_positive"
+	KeyAddFailed        Key = "add_failed"
+	KeyRemoveFailed     Key = "remove_failed"
+	KeyGetFailed        Key = "get_failed"
+	KeyCheckFailed      Key = "check_failed"
+	KeyCanceled         Key = "canceled"
+	KeyDeadlineExceeded Key = "deadline_exceeded"
+	KeyNotFound         Key = "not_found"
+	KeyAlreadyExists    Key = "already_exists"
+	KeyUnavailable      Key = "unavailable"
+	KeyInternal         Key = "internal"
+	KeyUnauthenticated  Key = "unauthenticated"
+	KeyPermissionDenied Key = "permission_denied"
+	KeyClientNotAllowed Key = "client_not_allowed"
+	KeyRateLimited      Key = "rate_limited"
+)
+
+// catalog — тексты сообщений по языкам. Параметры подставляются вместо {имя}.
+var catalog = map[language.Tag]map[Key]string{
+	language.Russian: {
+		KeyInvalidIDs:       "media_id и user_id должны быть положительными числами",
+		KeyInvalidUserID:    "user_id должен быть положительным числом",
+		KeyMustBePositive:   "должен быть положительным числом",
+		KeyAddFailed:        "ошибка при добавлении медиа {media_id} в watchlist",
+		KeyRemoveFailed:     "ошибка при удалении медиа {media_id} из watchlist",
+		KeyGetFailed:        "ошибка при получении watchlist",
+		KeyCheckFailed:      "ошибка при проверке наличия медиа {media_id} в watchlist",
+		KeyCanceled:         "запрос отменён",
+		KeyDeadlineExceeded: "превышено время ожидания запроса",
+		KeyNotFound:         "запись не найдена",
+		KeyAlreadyExists:    "запись уже существует",
+		KeyUnavailable:      "сервис временно недоступен",
+		KeyInternal:         "внутренняя ошибка сервера",
+		KeyUnauthenticated:  "требуется действительный токен доступа",
+		KeyPermissionDenied: "доступ к списку просмотра другого пользователя запрещён",
+		KeyClientNotAllowed: "клиент не входит в список разрешённых",
+		KeyRateLimited:      "слишком много запросов, повторите через {seconds} с",
+	},
+	language.English: {
+		KeyInvalidIDs:       "media_id and user_id must be positive integers",
+		KeyInvalidUserID:    "user_id must be a positive integer",
+		KeyMustBePositive:   "must be a positive integer",
+		KeyAddFailed:        "failed to add media {media_id} to the watchlist",
+		KeyRemoveFailed:     "failed to remove media {media_id} from the watchlist",
+		KeyGetFailed:        "failed to get the watchlist",
+		KeyCheckFailed:      "failed to check whether media {media_id} is in the watchlist",
+		KeyCanceled:         "request canceled",
+		KeyDeadlineExceeded: "request deadline exceeded",
+		KeyNotFound:         "record not found",
+		KeyAlreadyExists:    "record already exists",
+		KeyUnavailable:      "service is temporarily unavailable",
+		KeyInternal:         "internal server error",
+		KeyUnauthenticated:  "a valid access token is required",
+		KeyPermissionDenied: "access to another user's watchlist is denied",
+		KeyClientNotAllowed: "client is not in the allowed list",
+		KeyRateLimited:      "too many requests, retry in {seconds} s",
+	},
+}
@@ -0,0 +1,104 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/language"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// AcceptLanguageKey — ключ метаданных запроса с предпочтительными языками клиента
+const AcceptLanguageKey = "accept-language"
+
+// Fallback — язык сообщений, если язык запроса неизвестен
+var Fallback = language.Russian
+
+// Params — параметры сообщения, подставляются вместо {имя}
+type Params map[string]any
+
+// Translate возвращает текст сообщения на языке locale. Если сообщения нет на этом языке,
+// используется Fallback, если нет и там — сам ключ.
+func Translate(locale language.Tag, key Key, params Params) string {
+	text, ok := catalog[locale][key]
+	if !ok {
+		if text, ok = catalog[Fallback][key]; !ok {
+			text = string(key)
+		}
+	}
+
+	if len(params) == 0 {
+		return text
+	}
+	replacements := make([]string, 0, 2*len(params))
+	for name, value := range params {
+		replacements = append(replacements, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(replacements...).Replace(text)
+}
+
+// Resolver выбирает язык сообщений по заголовку Accept-Language из поддерживаемых каталогом
+type Resolver struct {
+	matcher language.Matcher
+	tags    []language.Tag
+}
+
+// NewResolver создает новый экземпляр Resolver. Язык defaultLocale используется,
+// если клиент не указал язык или ни один из указанных не поддерживается.
+func NewResolver(defaultLocale string) (*Resolver, error) {
+	def, err := language.Parse(defaultLocale)
+	if err != nil {
+		return nil, fmt.Errorf("invalid default locale %q: %w", defaultLocale, err)
+	}
+	base, _ := def.Base()
+	def = language.Make(base.String())
+	if _, ok := catalog[def]; !ok {
+		return nil, fmt.Errorf("default locale %q is not supported", defaultLocale)
+	}
+
+	tags := []language.Tag{def}
+	for tag := range catalog {
+		if tag != def {
+			tags = append(tags, tag)
+		}
+	}
+	return &Resolver{matcher: language.NewMatcher(tags), tags: tags}, nil
+}
+
+// Resolve возвращает язык сообщений для значения заголовка Accept-Language
+func (r *Resolver) Resolve(acceptLanguage string) language.Tag {
+	preferred, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	_, index, confidence := r.matcher.Match(preferred...)
+	if confidence == language.No {
+		// Без совпадений matcher может предложить английский, но нужен язык по умолчанию
+		return r.tags[0]
+	}
+	return r.tags[index]
+}
+
+// UnaryServerInterceptor определяет язык запроса по метаданным accept-language
+// и сохраняет его в контексте
+func (r *Resolver) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		return handler(WithLocale(ctx, r.Resolve(strings.Join(md.Get(AcceptLanguageKey), ","))), req)
+	}
+}
+
+// localeKey — ключ контекста для языка запроса
+type localeKey struct{}
+
+// WithLocale возвращает контекст с языком сообщений
+func WithLocale(ctx context.Context, locale language.Tag) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFromContext возвращает язык сообщений из контекста или Fallback
+func LocaleFromContext(ctx context.Context) language.Tag {
+	if locale, ok := ctx.Value(localeKey{}).(language.Tag); ok {
+		return locale
+	}
+	return Fallback
+}
@@ -0,0 +1,120 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"path"
+	"runtime/debug"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/watchlist-kata/watchlist/internal/apperror"
+	"github.com/watchlist-kata/watchlist/internal/auth"
+	"github.com/watchlist-kata/watchlist/internal/i18n"
+	"github.com/watchlist-kata/watchlist/pkg/logger"
+)
+
+// RequestIDKey — ключ метаданных запроса и ответа с идентификатором запроса
+const RequestIDKey = "x-request-id"
+
+// maxRequestIDLength — максимальная длина идентификатора запроса, принимаемого от клиента
+const maxRequestIDLength = 128
+
+// Recovery перехватывает панику в обработчике, логирует её со стеком вызовов
+// и возвращает клиенту ошибку Internal вместо падения процесса
+func Recovery(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.ErrorContext(ctx, fmt.Sprintf("panic in %s: %v", info.FullMethod, r), slog.String("stack", string(debug.Stack())))
+				resp, err = nil, apperror.ToGRPC(ctx, apperror.New(apperror.CodeInternal, i18n.KeyInternal))
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// RequestID берет идентификатор запроса из метаданных x-request-id или генерирует новый,
+// сохраняет его и метод вызова в контексте для логов и возвращает идентификатор клиенту в заголовке ответа
+func RequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		requestID := incomingRequestID(ctx)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, requestID))
+		ctx = logger.WithRPCMethod(logger.WithRequestID(ctx, requestID), info.FullMethod)
+		return handler(ctx, req)
+	}
+}
+
+// incomingRequestID возвращает идентификатор запроса из метаданных, если он допустим
+func incomingRequestID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(RequestIDKey)
+	if len(values) == 0 || len(values[0]) > maxRequestIDLength {
+		return ""
+	}
+	for _, c := range values[0] {
+		// Допускаются только печатные ASCII символы, чтобы значение было безопасно выводить в логи
+		if c < 0x21 || c > 0x7e {
+			return ""
+		}
+	}
+	return values[0]
+}
+
+// newRequestID генерирует случайный идентификатор запроса
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AccessLog пишет одну структурированную строку на каждый вызов: метод, код ответа,
+// длительность, адрес клиента и аутентифицированного пользователя. Пока enabled возвращает false,
+// вызовы не записываются; enabled равный nil означает, что журнал включен всегда.
+func AccessLog(log *slog.Logger, enabled func() bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if enabled != nil && !enabled() {
+			return handler(ctx, req)
+		}
+
+		// Пользователь берется из проверенного токена, а не из тела запроса, которое задает клиент
+		ctx, identity := auth.WithIdentityRecorder(ctx)
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		duration := time.Since(start)
+
+		code := status.Code(err)
+		attrs := []slog.Attr{
+			slog.String("method", info.FullMethod),
+			slog.String("code", code.String()),
+			slog.Duration("duration", duration),
+		}
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			attrs = append(attrs, slog.String("peer", p.Addr.String()))
+		}
+		if id, ok := identity(); ok {
+			attrs = append(attrs, slog.Int64("user_id", id.UserID))
+		}
+
+		level := slog.LevelInfo
+		switch code {
+		case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+			level = slog.LevelError
+		}
+
+		log.LogAttrs(ctx, level, fmt.Sprintf("%s %s %s", path.Base(info.FullMethod), code, duration), attrs...)
+		return resp, err
+	}
+}
@@ -0,0 +1,221 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+
+	"github.com/watchlist-kata/watchlist/pkg/logger"
+)
+
+// namespace — общий префикс имён метрик сервиса
+const namespace = "watchlist"
+
+// gormStartKey — ключ, под которым в gorm.DB сохраняется время начала запроса
+const gormStartKey = "metrics:start"
+
+// Metrics содержит реестр и метрики сервиса для Prometheus
+type Metrics struct {
+	registry        *prometheus.Registry
+	rpcRequests     *prometheus.CounterVec
+	rpcDuration     *prometheus.HistogramVec
+	dbQueryDuration *prometheus.HistogramVec
+}
+
+// New создает реестр метрик со стандартными метриками процесса и Go runtime
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		rpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "requests_total",
+			Help:      "Total number of gRPC requests by method and status code.",
+		}, []string{"method", "code"}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of gRPC requests by method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		dbQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Duration of database queries by operation, table and result.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table", "result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.rpcRequests,
+		m.rpcDuration,
+		m.dbQueryDuration,
+	)
+	return m
+}
+
+// Handler возвращает HTTP обработчик, отдающий метрики в формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// UnaryServerInterceptor считает gRPC запросы и измеряет их длительность
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		method := path.Base(info.FullMethod)
+		code := status.Code(err).String()
+		m.rpcRequests.WithLabelValues(method, code).Inc()
+		m.rpcDuration.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// InstrumentGorm регистрирует callbacks gorm, измеряющие длительность запросов к базе данных
+func (m *Metrics) InstrumentGorm(db *gorm.DB) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("gorm:create").Register("metrics:before_create", m.gormBefore),
+		callback.Create().After("gorm:create").Register("metrics:after_create", m.gormAfter("create")),
+		callback.Query().Before("gorm:query").Register("metrics:before_query", m.gormBefore),
+		callback.Query().After("gorm:query").Register("metrics:after_query", m.gormAfter("query")),
+		callback.Update().Before("gorm:update").Register("metrics:before_update", m.gormBefore),
+		callback.Update().After("gorm:update").Register("metrics:after_update", m.gormAfter("update")),
+		callback.Delete().Before("gorm:delete").Register("metrics:before_delete", m.gormBefore),
+		callback.Delete().After("gorm:delete").Register("metrics:after_delete", m.gormAfter("delete")),
+		callback.Row().Before("gorm:row").Register("metrics:before_row", m.gormBefore),
+		callback.Row().After("gorm:row").Register("metrics:after_row", m.gormAfter("row")),
+		callback.Raw().Before("gorm:raw").Register("metrics:before_raw", m.gormBefore),
+		callback.Raw().After("gorm:raw").Register("metrics:after_raw", m.gormAfter("raw")),
+	)
+}
+
+// gormBefore запоминает время начала запроса
+func (m *Metrics) gormBefore(db *gorm.DB) {
+	db.InstanceSet(gormStartKey, time.Now())
+}
+
+// gormAfter возвращает callback, записывающий длительность запроса указанного типа
+func (m *Metrics) gormAfter(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(gormStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		result := "ok"
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			result = "error"
+		}
+		m.dbQueryDuration.WithLabelValues(operation, db.Statement.Table, result).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterDBStats регистрирует метрики состояния пула соединений с базой данных
+func (m *Metrics) RegisterDBStats(db *sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// RegisterLogger регистрирует метрики буферов асинхронных обработчиков логгера
+func (m *Metrics) RegisterLogger(source LoggerStatsSource) {
+	m.registry.MustRegister(&loggerCollector{source: source})
+}
+
+// LoggerStatsSource предоставляет состояние буферов обработчиков логгера (например, *logger.MultiHandler)
+type LoggerStatsSource interface {
+	Stats() []logger.HandlerStats
+}
+
+var (
+	loggerQueueDepthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "logger", "queue_depth"),
+		"Number of log records waiting in the handler buffer.",
+		[]string{"handler"}, nil,
+	)
+	loggerQueueCapacityDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "logger", "queue_capacity"),
+		"Capacity of the handler buffer.",
+		[]string{"handler"}, nil,
+	)
+	loggerDroppedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "logger", "dropped_records_total"),
+		"Total number of log records dropped because the handler buffer was full.",
+		[]string{"handler"}, nil,
+	)
+	loggerSpooledDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "logger", "spooled_records_total"),
+		"Total number of log records stored in the disk spool because they could not be delivered.",
+		[]string{"handler"}, nil,
+	)
+	loggerReplayedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "logger", "replayed_records_total"),
+		"Total number of spooled log records delivered after the sink became reachable again.",
+		[]string{"handler"}, nil,
+	)
+	loggerSpoolErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "logger", "spool_errors_total"),
+		"Total number of failed reads, replays and writes of the disk spool.",
+		[]string{"handler"}, nil,
+	)
+	loggerSampledDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "logger", "sampled_records_total"),
+		"Total number of log records dropped by sampling.",
+		[]string{"handler"}, nil,
+	)
+	loggerDeduplicatedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "logger", "deduplicated_records_total"),
+		"Total number of repeated log records collapsed into a summary.",
+		[]string{"handler"}, nil,
+	)
+)
+
+// loggerCollector снимает состояние буферов логгера в момент сбора метрик
+type loggerCollector struct {
+	source LoggerStatsSource
+}
+
+// Describe отправляет описания метрик логгера
+func (c *loggerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- loggerQueueDepthDesc
+	ch <- loggerQueueCapacityDesc
+	ch <- loggerDroppedDesc
+	ch <- loggerSpooledDesc
+	ch <- loggerReplayedDesc
+	ch <- loggerSpoolErrorsDesc
+	ch <- loggerSampledDesc
+	ch <- loggerDeduplicatedDesc
+}
+
+// Collect отправляет текущие значения метрик логгера
+func (c *loggerCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, stats := range c.source.Stats() {
+		ch <- prometheus.MustNewConstMetric(loggerQueueDepthDesc, prometheus.GaugeValue, float64(stats.Depth), stats.Name)
+		ch <- prometheus.MustNewConstMetric(loggerQueueCapacityDesc, prometheus.GaugeValue, float64(stats.Capacity), stats.Name)
+		ch <- prometheus.MustNewConstMetric(loggerDroppedDesc, prometheus.CounterValue, float64(stats.Dropped), stats.Name)
+		ch <- prometheus.MustNewConstMetric(loggerSpooledDesc, prometheus.CounterValue, float64(stats.Spooled), stats.Name)
+		ch <- prometheus.MustNewConstMetric(loggerReplayedDesc, prometheus.CounterValue, float64(stats.Replayed), stats.Name)
+		ch <- prometheus.MustNewConstMetric(loggerSpoolErrorsDesc, prometheus.CounterValue, float64(stats.SpoolErrors), stats.Name)
+		ch <- prometheus.MustNewConstMetric(loggerSampledDesc, prometheus.CounterValue, float64(stats.Sampled), stats.Name)
+		ch <- prometheus.MustNewConstMetric(loggerDeduplicatedDesc, prometheus.CounterValue, float64(stats.Deduplicated), stats.Name)
+	}
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval — как часто удаляются корзины, которые успели заполниться и не нужны
+const sweepInterval = time.Minute
+
+// bucket — состояние token bucket
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// take пополняет корзину на момент now и забирает токен, если он есть.
+// Возвращает false и время до появления следующего токена, если токенов нет.
+func (b *bucket) take(now time.Time, rule Rule) (bool, time.Duration) {
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	b.tokens = min(float64(rule.Burst), b.tokens+elapsed*rule.Rate)
+	b.updatedAt = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if rule.Rate <= 0 {
+		return false, time.Hour
+	}
+	return false, time.Duration((1 - b.tokens) / rule.Rate * float64(time.Second))
+}
+
+// MemoryLimiter хранит корзины в памяти процесса
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	rules     map[string]Rule
+	lastSweep time.Time
+}
+
+// NewMemoryLimiter создает новый экземпляр MemoryLimiter
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   make(map[string]*bucket),
+		rules:     make(map[string]Rule),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow забирает токен из корзины ключа
+func (l *MemoryLimiter) Allow(_ context.Context, key string, rule Rule) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst), updatedAt: now}
+		l.buckets[key] = b
+	}
+	l.rules[key] = rule
+
+	allowed, retryAfter := b.take(now, rule)
+	return allowed, retryAfter, nil
+}
+
+// sweep удаляет корзины, которые к моменту now уже полностью пополнились бы:
+// новая корзина для такого ключа ведет себя так же
+func (l *MemoryLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		rule := l.rules[key]
+		if rule.Rate <= 0 {
+			continue
+		}
+		if b.tokens+now.Sub(b.updatedAt).Seconds()*rule.Rate >= float64(rule.Burst) {
+			delete(l.buckets, key)
+			delete(l.rules, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GormBucket — корзина token bucket, общая для всех реплик сервиса
+type GormBucket struct {
+	Key       string `gorm:"primaryKey"`
+	Tokens    float64
+	Rate      float64 // Правило последнего запроса, по которому удаляются пополнившиеся корзины
+	Burst     int
+	UpdatedAt time.Time
+}
+
+// TableName возвращает имя таблицы для модели GormBucket
+func (GormBucket) TableName() string {
+	return "rate_limit_buckets"
+}
+
+// PostgresLimiter хранит корзины в PostgreSQL, чтобы лимиты соблюдались суммарно по всем репликам.
+// Если база данных недоступна, используется ограничитель в памяти процесса.
+type PostgresLimiter struct {
+	db       *gorm.DB
+	fallback *MemoryLimiter
+	logger   *slog.Logger
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewPostgresLimiter создает новый экземпляр PostgresLimiter и таблицу корзин, если её нет
+func NewPostgresLimiter(ctx context.Context, db *gorm.DB, logger *slog.Logger) (*PostgresLimiter, error) {
+	if err := db.WithContext(ctx).AutoMigrate(&GormBucket{}); err != nil {
+		return nil, fmt.Errorf("failed to create rate limit table: %w", err)
+	}
+	return &PostgresLimiter{db: db, fallback: NewMemoryLimiter(), logger: logger, lastSweep: time.Now()}, nil
+}
+
+// Allow забирает токен из корзины ключа в транзакции с блокировкой строки.
+// Время пополнения считается по часам базы данных, чтобы расхождение часов реплик не влияло на лимит.
+func (l *PostgresLimiter) Allow(ctx context.Context, key string, rule Rule) (bool, time.Duration, error) {
+	l.sweepIfDue(ctx)
+
+	var allowed bool
+	var retryAfter time.Duration
+
+	err := l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(
+			"INSERT INTO rate_limit_buckets (key, tokens, rate, burst, updated_at) VALUES (?, ?, ?, ?, now()) ON CONFLICT (key) DO NOTHING",
+			key, rule.Burst, rule.Rate, rule.Burst,
+		).Error; err != nil {
+			return err
+		}
+
+		var state struct {
+			Tokens  float64
+			Elapsed float64
+		}
+		if err := tx.Raw(
+			"SELECT tokens, EXTRACT(EPOCH FROM now() - updated_at)::float8 AS elapsed FROM rate_limit_buckets WHERE key = ? FOR UPDATE",
+			key,
+		).Scan(&state).Error; err != nil {
+			return err
+		}
+
+		b := &bucket{tokens: state.Tokens}
+		allowed, retryAfter = b.take(b.updatedAt.Add(time.Duration(state.Elapsed*float64(time.Second))), rule)
+
+		return tx.Exec("UPDATE rate_limit_buckets SET tokens = ?, rate = ?, burst = ?, updated_at = now() WHERE key = ?",
+			b.tokens, rule.Rate, rule.Burst, key).Error
+	})
+	if err != nil {
+		l.logger.WarnContext(ctx, "shared rate limiter is unavailable, using in-process limiter", slog.Any("error", err))
+		return l.fallback.Allow(ctx, key, rule)
+	}
+	return allowed, retryAfter, nil
+}
+
+// sweepIfDue раз в sweepInterval удаляет корзины, которые уже полностью пополнились бы:
+// новая корзина для такого ключа ведет себя так же. Удаление выполняет каждая реплика,
+// повторное удаление безопасно.
+func (l *PostgresLimiter) sweepIfDue(ctx context.Context) {
+	l.mu.Lock()
+	now := time.Now()
+	if now.Sub(l.lastSweep) < sweepInterval {
+		l.mu.Unlock()
+		return
+	}
+	l.lastSweep = now
+	l.mu.Unlock()
+
+	if err := l.db.WithContext(ctx).Exec(
+		"DELETE FROM rate_limit_buckets WHERE rate > 0 AND tokens + EXTRACT(EPOCH FROM now() - updated_at)::float8 * rate >= burst",
+	).Error; err != nil {
+		l.logger.WarnContext(ctx, "failed to delete refilled rate limit buckets", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,168 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/watchlist-kata/watchlist/internal/apperror"
+	"github.com/watchlist-kata/watchlist/internal/auth"
+	"github.com/watchlist-kata/watchlist/internal/i18n"
+)
+
+// RetryAfterKey — ключ метаданных ответа с рекомендуемой задержкой в секундах перед повтором запроса
+const RetryAfterKey = "retry-after"
+
+// ForwardedForKey — ключ метаданных с адресом HTTP клиента, который передает REST шлюз
+const ForwardedForKey = "x-forwarded-for"
+
+// GatewayNetwork — сеть in-memory соединения, через которое REST шлюз обращается к gRPC серверу.
+// Только для соединений этой сети адрес клиента берется из метаданных ForwardedForKey.
+const GatewayNetwork = "gateway"
+
+// Rule задает параметры token bucket
+type Rule struct {
+	Rate  float64 // Пополнение корзины, токенов в секунду
+	Burst int     // Емкость корзины
+}
+
+// Limiter решает, можно ли выполнить запрос для ключа по правилу
+type Limiter interface {
+	// Allow забирает токен из корзины ключа. Если токенов нет, возвращает false
+	// и время, через которое появится следующий токен.
+	Allow(ctx context.Context, key string, rule Rule) (bool, time.Duration, error)
+}
+
+// userIDRequest — запрос, относящийся к списку просмотра конкретного пользователя
+type userIDRequest interface {
+	GetUserId() int64
+}
+
+// Interceptor ограничивает частоту вызовов методов отдельно для каждого пользователя и каждого клиента.
+// Правила задаются по коротким именам методов, например AddToWatchlist.
+type Interceptor struct {
+	limiter     Limiter
+	mu          sync.RWMutex
+	userRules   map[string]Rule
+	clientRules map[string]Rule
+	logger      *slog.Logger
+}
+
+// NewInterceptor создает новый экземпляр Interceptor
+func NewInterceptor(limiter Limiter, userRules, clientRules map[string]Rule, logger *slog.Logger) *Interceptor {
+	return &Interceptor{
+		limiter:     limiter,
+		userRules:   userRules,
+		clientRules: clientRules,
+		logger:      logger,
+	}
+}
+
+// SetRules заменяет правила без перезапуска сервера. Состояние корзин сохраняется,
+// поэтому новые правила применяются к уже накопленным токенам.
+func (i *Interceptor) SetRules(userRules, clientRules map[string]Rule) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.userRules = userRules
+	i.clientRules = clientRules
+}
+
+// rules возвращает правила метода для клиента и для пользователя
+func (i *Interceptor) rules(method string) (Rule, bool, Rule, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	clientRule, clientOK := i.clientRules[method]
+	userRule, userOK := i.userRules[method]
+	return clientRule, clientOK, userRule, userOK
+}
+
+// UnaryServerInterceptor отклоняет запросы сверх лимита с кодом ResourceExhausted
+// и метаданными retry-after
+func (i *Interceptor) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		method := path.Base(info.FullMethod)
+		clientRule, clientOK, userRule, userOK := i.rules(method)
+
+		if clientOK {
+			if err := i.check(ctx, "client:"+method+":"+clientKey(ctx), clientRule); err != nil {
+				return nil, err
+			}
+		}
+
+		if userOK {
+			if userID, ok := userKey(ctx, req); ok {
+				if err := i.check(ctx, "user:"+method+":"+userID, userRule); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// check забирает токен для ключа и возвращает ошибку ResourceExhausted, если лимит исчерпан
+func (i *Interceptor) check(ctx context.Context, key string, rule Rule) error {
+	allowed, retryAfter, err := i.limiter.Allow(ctx, key, rule)
+	if err != nil {
+		// Ошибка ограничителя не должна блокировать запросы
+		i.logger.WarnContext(ctx, fmt.Sprintf("rate limiter failed for key %s", key), slog.Any("error", err))
+		return nil
+	}
+	if allowed {
+		return nil
+	}
+
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	grpc.SetTrailer(ctx, metadata.Pairs(RetryAfterKey, strconv.Itoa(seconds)))
+
+	i.logger.WarnContext(ctx, fmt.Sprintf("rate limit exceeded for key %s", key), slog.Duration("retry_after", retryAfter))
+	return apperror.ToGRPC(ctx, apperror.New(apperror.CodeRateLimited, i18n.KeyRateLimited).
+		WithParams(i18n.Params{"seconds": seconds}).
+		WithRetryAfter(time.Duration(seconds)*time.Second))
+}
+
+// userKey возвращает пользователя запроса: из токена, если запрос аутентифицирован, иначе из запроса
+func userKey(ctx context.Context, req interface{}) (string, bool) {
+	if identity, ok := auth.IdentityFromContext(ctx); ok {
+		return strconv.FormatInt(identity.UserID, 10), true
+	}
+	if r, ok := req.(userIDRequest); ok && r.GetUserId() > 0 {
+		return strconv.FormatInt(r.GetUserId(), 10), true
+	}
+	return "", false
+}
+
+// clientKey возвращает идентификатор клиента: имя из сертификата mTLS или IP адрес.
+// Для запросов REST шлюза, пришедших через in-memory соединение, используется адрес HTTP клиента
+// из метаданных; от остальных клиентов эти метаданные не принимаются, чтобы адрес нельзя было подменить.
+func clientKey(ctx context.Context) string {
+	if client, ok := auth.ClientFromContext(ctx); ok {
+		return client
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if p.Addr.Network() == GatewayNetwork {
+			if forwarded := metadata.ValueFromIncomingContext(ctx, ForwardedForKey); len(forwarded) > 0 && forwarded[0] != "" {
+				return forwarded[0]
+			}
+		}
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+		return p.Addr.String()
+	}
+	return "unknown"
+}
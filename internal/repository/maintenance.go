@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Stats — сводные данные по спискам просмотра
+type Stats struct {
+	Items  int64      `json:"items"`
+	Users  int64      `json:"users"`
+	Media  int64      `json:"media"`
+	Oldest *time.Time `json:"oldest,omitempty"`
+	Newest *time.Time `json:"newest,omitempty"`
+}
+
+// Migrate создает или обновляет схему базы данных
+func (r *PostgresRepository) Migrate(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "Migrate")
+	defer func() { endSpan(span, err) }()
+
+	if err := r.db.WithContext(ctx).AutoMigrate(&GormWatchlist{}); err != nil {
+		r.logger.ErrorContext(ctx, "failed to migrate database schema", slog.Any("error", err))
+		return err
+	}
+
+	r.logger.InfoContext(ctx, "database schema migrated successfully")
+	return nil
+}
+
+// CountUser возвращает число записей в списке просмотра пользователя
+func (r *PostgresRepository) CountUser(ctx context.Context, userID uint) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "CountUser", attribute.Int("user_id", int(userID)))
+	defer func() { endSpan(span, err) }()
+
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&GormWatchlist{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		r.logger.ErrorContext(ctx, fmt.Sprintf("failed to count watchlist for user ID: %d", userID), slog.Any("error", err))
+		return 0, err
+	}
+	return count, nil
+}
+
+// PurgeUser удаляет весь список просмотра пользователя и возвращает число удаленных записей
+func (r *PostgresRepository) PurgeUser(ctx context.Context, userID uint) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "PurgeUser", attribute.Int("user_id", int(userID)))
+	defer func() { endSpan(span, err) }()
+
+	result := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&GormWatchlist{})
+	if result.Error != nil {
+		r.logger.ErrorContext(ctx, fmt.Sprintf("failed to purge watchlist for user ID: %d", userID), slog.Any("error", result.Error))
+		return 0, result.Error
+	}
+
+	r.logger.InfoContext(ctx, fmt.Sprintf("watchlist purged for user ID: %d, %d records removed", userID, result.RowsAffected))
+	return result.RowsAffected, nil
+}
+
+// Stats возвращает сводные данные по всем спискам просмотра
+func (r *PostgresRepository) Stats(ctx context.Context) (_ Stats, err error) {
+	ctx, span := startSpan(ctx, "Stats")
+	defer func() { endSpan(span, err) }()
+
+	var stats Stats
+	if err := r.db.WithContext(ctx).Model(&GormWatchlist{}).Select(
+		"COUNT(*) AS items, COUNT(DISTINCT user_id) AS users, COUNT(DISTINCT media_id) AS media, MIN(created_at) AS oldest, MAX(created_at) AS newest",
+	).Scan(&stats).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to collect watchlist stats", slog.Any("error", err))
+		return Stats{}, err
+	}
+	return stats, nil
+}
@@ -6,14 +6,23 @@ import (
 	"fmt"
 	"log/slog"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
+// tracer создает спаны для запросов репозитория к базе данных
+var tracer = otel.Tracer("github.com/watchlist-kata/watchlist/internal/repository")
+
 var (
 	// ErrRecordNotFound возвращается, когда запись не найдена
 	ErrRecordNotFound = errors.New("record not found")
 	// ErrDuplicateEntry возвращается при попытке создать дублирующуюся запись
 	ErrDuplicateEntry = errors.New("duplicate entry")
+	// ErrMigrationsPending возвращается, когда схема базы данных ещё не создана
+	ErrMigrationsPending = errors.New("database migrations are pending")
 )
 
 // WatchlistRepository представляет интерфейс репозитория для работы со списками просмотра
@@ -35,8 +44,35 @@ func NewPostgresRepository(db *gorm.DB, logger *slog.Logger) *PostgresRepository
 	return &PostgresRepository{db: db, logger: logger}
 }
 
+// startSpan начинает спан операции репозитория
+func startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("db.system", "postgresql"), attribute.String("db.sql.table", GormWatchlist{}.TableName()))
+	return tracer.Start(ctx, "PostgresRepository."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endSpan завершает спан и отмечает в нём неожиданные ошибки.
+// ErrRecordNotFound и ErrDuplicateEntry — ожидаемые результаты и ошибками спана не считаются.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, ErrRecordNotFound) && !errors.Is(err, ErrDuplicateEntry) {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.End()
+}
+
+// CheckSchema проверяет, что схема базы данных готова к работе
+func (r *PostgresRepository) CheckSchema(ctx context.Context) error {
+	if !r.db.WithContext(ctx).Migrator().HasTable(&GormWatchlist{}) {
+		return ErrMigrationsPending
+	}
+	return nil
+}
+
 // AddToWatchlist добавляет медиа в список просмотра пользователя
-func (r *PostgresRepository) AddToWatchlist(ctx context.Context, watchlist *GormWatchlist) error {
+func (r *PostgresRepository) AddToWatchlist(ctx context.Context, watchlist *GormWatchlist) (err error) {
+	ctx, span := startSpan(ctx, "AddToWatchlist", attribute.Int("media_id", int(watchlist.MediaID)), attribute.Int("user_id", int(watchlist.UserID)))
+	defer func() { endSpan(span, err) }()
+
 	// Проверка отмены контекста
 	select {
 	case <-ctx.Done():
@@ -59,7 +95,7 @@ func (r *PostgresRepository) AddToWatchlist(ctx context.Context, watchlist *Gorm
 	}
 
 	// Создаем новую запись
-	if err := r.db.Create(watchlist).Error; err != nil {
+	if err := r.db.WithContext(ctx).Create(watchlist).Error; err != nil {
 		r.logger.ErrorContext(ctx, fmt.Sprintf("failed to add media to watchlist for media ID: %d and user ID: %d", watchlist.MediaID, watchlist.UserID), slog.Any("error", err))
 		return err
 	}
@@ -69,7 +105,10 @@ func (r *PostgresRepository) AddToWatchlist(ctx context.Context, watchlist *Gorm
 }
 
 // RemoveFromWatchlist удаляет медиа из списка просмотра пользователя
-func (r *PostgresRepository) RemoveFromWatchlist(ctx context.Context, mediaID uint, userID uint) error {
+func (r *PostgresRepository) RemoveFromWatchlist(ctx context.Context, mediaID uint, userID uint) (err error) {
+	ctx, span := startSpan(ctx, "RemoveFromWatchlist", attribute.Int("media_id", int(mediaID)), attribute.Int("user_id", int(userID)))
+	defer func() { endSpan(span, err) }()
+
 	// Проверка отмены контекста
 	select {
 	case <-ctx.Done():
@@ -92,7 +131,7 @@ func (r *PostgresRepository) RemoveFromWatchlist(ctx context.Context, mediaID ui
 	}
 
 	// Удаляем запись
-	if err := r.db.Where("media_id = ? AND user_id = ?", mediaID, userID).Delete(&GormWatchlist{}).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("media_id = ? AND user_id = ?", mediaID, userID).Delete(&GormWatchlist{}).Error; err != nil {
 		r.logger.ErrorContext(ctx, fmt.Sprintf("failed to remove media from watchlist for media ID: %d and user ID: %d", mediaID, userID), slog.Any("error", err))
 		return err
 	}
@@ -102,7 +141,10 @@ func (r *PostgresRepository) RemoveFromWatchlist(ctx context.Context, mediaID ui
 }
 
 // GetWatchlist получает список просмотра пользователя
-func (r *PostgresRepository) GetWatchlist(ctx context.Context, userID uint) ([]GormWatchlist, error) {
+func (r *PostgresRepository) GetWatchlist(ctx context.Context, userID uint) (_ []GormWatchlist, err error) {
+	ctx, span := startSpan(ctx, "GetWatchlist", attribute.Int("user_id", int(userID)))
+	defer func() { endSpan(span, err) }()
+
 	// Проверка отмены контекста
 	select {
 	case <-ctx.Done():
@@ -112,7 +154,7 @@ func (r *PostgresRepository) GetWatchlist(ctx context.Context, userID uint) ([]G
 	}
 
 	var watchlists []GormWatchlist
-	if err := r.db.Where("user_id = ?", userID).Find(&watchlists).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&watchlists).Error; err != nil {
 		r.logger.ErrorContext(ctx, fmt.Sprintf("failed to get watchlist for user ID: %d", userID), slog.Any("error", err))
 		return nil, err
 	}
@@ -122,7 +164,10 @@ func (r *PostgresRepository) GetWatchlist(ctx context.Context, userID uint) ([]G
 }
 
 // CheckInWatchlist проверяет, находится ли медиа в списке просмотра пользователя
-func (r *PostgresRepository) CheckInWatchlist(ctx context.Context, mediaID uint, userID uint) (bool, error) {
+func (r *PostgresRepository) CheckInWatchlist(ctx context.Context, mediaID uint, userID uint) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "CheckInWatchlist", attribute.Int("media_id", int(mediaID)), attribute.Int("user_id", int(userID)))
+	defer func() { endSpan(span, err) }()
+
 	// Проверка отмены контекста
 	select {
 	case <-ctx.Done():
@@ -132,7 +177,7 @@ func (r *PostgresRepository) CheckInWatchlist(ctx context.Context, mediaID uint,
 	}
 
 	var count int64
-	if err := r.db.Model(&GormWatchlist{}).Where("media_id = ? AND user_id = ?", mediaID, userID).Count(&count).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&GormWatchlist{}).Where("media_id = ? AND user_id = ?", mediaID, userID).Count(&count).Error; err != nil {
 		r.logger.ErrorContext(ctx, fmt.Sprintf("failed to check media in watchlist for media ID: %d and user ID: %d", mediaID, userID), slog.Any("error", err))
 		return false, err
 	}
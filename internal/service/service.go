@@ -7,10 +7,9 @@ import (
 	"log/slog"
 	"time"
 
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
-
 	"github.com/watchlist-kata/protos/watchlist"
+	"github.com/watchlist-kata/watchlist/internal/apperror"
+	"github.com/watchlist-kata/watchlist/internal/i18n"
 	"github.com/watchlist-kata/watchlist/internal/repository"
 )
 
@@ -37,16 +36,49 @@ func (s *WatchlistService) checkContextCancelled(ctx context.Context, method str
 	}
 }
 
+// validateIDs проверяет, что идентификаторы медиа и пользователя положительные
+func validateIDs(mediaID, userID int64) error {
+	var violations []apperror.FieldViolation
+	if mediaID <= 0 {
+		violations = append(violations, apperror.FieldViolation{Field: "media_id", Key: i18n.KeyMustBePositive})
+	}
+	if userID <= 0 {
+		violations = append(violations, apperror.FieldViolation{Field: "user_id", Key: i18n.KeyMustBePositive})
+	}
+	if len(violations) > 0 {
+		return apperror.Validation(i18n.KeyInvalidIDs, violations...)
+	}
+	return nil
+}
+
+// validateUserID проверяет, что идентификатор пользователя положительный
+func validateUserID(userID int64) error {
+	if userID <= 0 {
+		return apperror.Validation(i18n.KeyInvalidUserID,
+			apperror.FieldViolation{Field: "user_id", Key: i18n.KeyMustBePositive})
+	}
+	return nil
+}
+
+// repositoryError переводит ошибку репозитория в gRPC статус.
+// Непредвиденные ошибки получают сообщение key, текст исходной ошибки клиенту не передается.
+func repositoryError(ctx context.Context, err error, key i18n.Key, params i18n.Params) error {
+	if apperror.From(err).Code == apperror.CodeInternal {
+		err = apperror.Wrap(apperror.CodeInternal, key, err).WithParams(params)
+	}
+	return apperror.ToGRPC(ctx, err)
+}
+
 // AddToWatchlist добавляет медиа в список просмотра пользователя
 func (s *WatchlistService) AddToWatchlist(ctx context.Context, req *watchlist.AddToWatchlistRequest) (*watchlist.AddToWatchlistResponse, error) {
 	if err := s.checkContextCancelled(ctx, "AddToWatchlist"); err != nil {
-		return nil, status.Error(codes.Canceled, err.Error())
+		return nil, apperror.ToGRPC(ctx, err)
 	}
 
 	// Проверка входных данных
-	if req.MediaId <= 0 || req.UserId <= 0 {
+	if err := validateIDs(req.MediaId, req.UserId); err != nil {
 		s.logger.WarnContext(ctx, "invalid media_id or user_id: must be positive integers")
-		return nil, status.Error(codes.InvalidArgument, "media_id и user_id должны быть положительными числами")
+		return nil, apperror.ToGRPC(ctx, err)
 	}
 
 	watchlistItem := &repository.GormWatchlist{
@@ -63,7 +95,7 @@ func (s *WatchlistService) AddToWatchlist(ctx context.Context, req *watchlist.Ad
 			return &watchlist.AddToWatchlistResponse{Success: true}, nil
 		}
 		s.logger.ErrorContext(ctx, fmt.Sprintf("failed to add media to watchlist for media ID: %d and user ID: %d", req.MediaId, req.UserId), slog.Any("error", err))
-		return nil, status.Errorf(codes.Internal, "ошибка при добавлении в watchlist: %v", err)
+		return nil, repositoryError(ctx, err, i18n.KeyAddFailed, i18n.Params{"media_id": req.MediaId})
 	}
 
 	s.logger.InfoContext(ctx, fmt.Sprintf("media added to watchlist successfully for media ID: %d and user ID: %d", req.MediaId, req.UserId))
@@ -73,13 +105,13 @@ func (s *WatchlistService) AddToWatchlist(ctx context.Context, req *watchlist.Ad
 // RemoveFromWatchlist удаляет медиа из списка просмотра пользователя
 func (s *WatchlistService) RemoveFromWatchlist(ctx context.Context, req *watchlist.RemoveFromWatchlistRequest) (*watchlist.RemoveFromWatchlistResponse, error) {
 	if err := s.checkContextCancelled(ctx, "RemoveFromWatchlist"); err != nil {
-		return nil, status.Error(codes.Canceled, err.Error())
+		return nil, apperror.ToGRPC(ctx, err)
 	}
 
 	// Проверка входных данных
-	if req.MediaId <= 0 || req.UserId <= 0 {
+	if err := validateIDs(req.MediaId, req.UserId); err != nil {
 		s.logger.WarnContext(ctx, "invalid media_id or user_id: must be positive integers")
-		return nil, status.Error(codes.InvalidArgument, "media_id и user_id должны быть положительными числами")
+		return nil, apperror.ToGRPC(ctx, err)
 	}
 
 	err := s.repo.RemoveFromWatchlist(ctx, uint(req.MediaId), uint(req.UserId))
@@ -90,7 +122,7 @@ func (s *WatchlistService) RemoveFromWatchlist(ctx context.Context, req *watchli
 			return &watchlist.RemoveFromWatchlistResponse{Success: false}, nil
 		}
 		s.logger.ErrorContext(ctx, fmt.Sprintf("failed to remove media from watchlist for media ID: %d and user ID: %d", req.MediaId, req.UserId), slog.Any("error", err))
-		return nil, status.Errorf(codes.Internal, "ошибка при удалении из watchlist: %v", err)
+		return nil, repositoryError(ctx, err, i18n.KeyRemoveFailed, i18n.Params{"media_id": req.MediaId})
 	}
 
 	s.logger.InfoContext(ctx, fmt.Sprintf("media removed from watchlist successfully for media ID: %d and user ID: %d", req.MediaId, req.UserId))
@@ -100,19 +132,19 @@ func (s *WatchlistService) RemoveFromWatchlist(ctx context.Context, req *watchli
 // GetWatchlist получает список просмотра пользователя
 func (s *WatchlistService) GetWatchlist(ctx context.Context, req *watchlist.GetWatchlistRequest) (*watchlist.GetWatchlistResponse, error) {
 	if err := s.checkContextCancelled(ctx, "GetWatchlist"); err != nil {
-		return nil, status.Error(codes.Canceled, err.Error())
+		return nil, apperror.ToGRPC(ctx, err)
 	}
 
 	// Проверка входных данных
-	if req.UserId <= 0 {
+	if err := validateUserID(req.UserId); err != nil {
 		s.logger.WarnContext(ctx, "invalid user_id: must be a positive integer")
-		return nil, status.Error(codes.InvalidArgument, "user_id должен быть положительным числом")
+		return nil, apperror.ToGRPC(ctx, err)
 	}
 
 	gormWatchlists, err := s.repo.GetWatchlist(ctx, uint(req.UserId))
 	if err != nil {
 		s.logger.ErrorContext(ctx, fmt.Sprintf("failed to get watchlist for user ID: %d", req.UserId), slog.Any("error", err))
-		return nil, status.Errorf(codes.Internal, "ошибка при получении watchlist: %v", err)
+		return nil, repositoryError(ctx, err, i18n.KeyGetFailed, nil)
 	}
 
 	watchlistItems := make([]*watchlist.WatchlistItem, 0, len(gormWatchlists))
@@ -132,19 +164,19 @@ func (s *WatchlistService) GetWatchlist(ctx context.Context, req *watchlist.GetW
 // CheckInWatchlist проверяет, находится ли медиа в списке просмотра пользователя
 func (s *WatchlistService) CheckInWatchlist(ctx context.Context, req *watchlist.CheckInWatchlistRequest) (*watchlist.CheckInWatchlistResponse, error) {
 	if err := s.checkContextCancelled(ctx, "CheckInWatchlist"); err != nil {
-		return nil, status.Error(codes.Canceled, err.Error())
+		return nil, apperror.ToGRPC(ctx, err)
 	}
 
 	// Проверка входных данных
-	if req.MediaId <= 0 || req.UserId <= 0 {
+	if err := validateIDs(req.MediaId, req.UserId); err != nil {
 		s.logger.WarnContext(ctx, "invalid media_id or user_id: must be positive integers")
-		return nil, status.Error(codes.InvalidArgument, "media_id и user_id должны быть положительными числами")
+		return nil, apperror.ToGRPC(ctx, err)
 	}
 
 	inWatchlist, err := s.repo.CheckInWatchlist(ctx, uint(req.MediaId), uint(req.UserId))
 	if err != nil {
 		s.logger.ErrorContext(ctx, fmt.Sprintf("failed to check media in watchlist for media ID: %d and user ID: %d", req.MediaId, req.UserId), slog.Any("error", err))
-		return nil, status.Errorf(codes.Internal, "ошибка при проверке наличия в watchlist: %v", err)
+		return nil, repositoryError(ctx, err, i18n.KeyCheckFailed, i18n.Params{"media_id": req.MediaId})
 	}
 
 	s.logger.InfoContext(ctx, fmt.Sprintf("media checked in watchlist for media ID: %d and user ID: %d", req.MediaId, req.UserId))
@@ -0,0 +1,170 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Options содержит параметры TLS сервера
+type Options struct {
+	CertFile       string        // Сертификат сервера в PEM
+	KeyFile        string        // Закрытый ключ сервера в PEM
+	ClientCAFile   string        // Сертификаты CA для проверки клиентов (mTLS)
+	ClientAuth     string        // Проверка клиентов: none, request, verify_if_given или require
+	ReloadInterval time.Duration // Интервал проверки файлов на изменения
+}
+
+// Reloader отдает TLS конфигурацию сервера и перечитывает сертификаты при изменении файлов,
+// поэтому ротация сертификатов не требует перезапуска
+type Reloader struct {
+	opts       Options
+	clientAuth tls.ClientAuthType
+	logger     *slog.Logger
+
+	mu        sync.RWMutex
+	config    *tls.Config
+	modTimes  map[string]time.Time
+	checkedAt time.Time
+}
+
+// NewReloader загружает сертификаты и создает Reloader
+func NewReloader(opts Options, logger *slog.Logger) (*Reloader, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, errors.New("both certificate and key files must be set")
+	}
+
+	clientAuth, err := parseClientAuth(opts.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+	if clientAuth >= tls.VerifyClientCertIfGiven && opts.ClientCAFile == "" {
+		return nil, fmt.Errorf("client CA file is required for client auth %q", opts.ClientAuth)
+	}
+
+	r := &Reloader{opts: opts, clientAuth: clientAuth, logger: logger}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ServerConfig возвращает TLS конфигурацию, которая при каждом подключении использует актуальные сертификаты
+func (r *Reloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		NextProtos:         []string{"h2"},
+		GetConfigForClient: r.getConfigForClient,
+	}
+}
+
+// getConfigForClient проверяет файлы на изменения (не чаще интервала) и возвращает текущую конфигурацию
+func (r *Reloader) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mu.RLock()
+	due := time.Since(r.checkedAt) >= r.opts.ReloadInterval
+	r.mu.RUnlock()
+
+	if due && r.changed() {
+		if err := r.reload(); err != nil {
+			// Продолжаем работать с предыдущими сертификатами
+			r.logger.Error("failed to reload TLS certificates", slog.Any("error", err))
+		} else {
+			r.logger.Info("TLS certificates reloaded")
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config, nil
+}
+
+// changed сообщает, изменилось ли время модификации какого-либо из файлов
+func (r *Reloader) changed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checkedAt = time.Now()
+	for _, path := range r.files() {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(r.modTimes[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+// reload читает сертификаты и строит новую конфигурацию
+func (r *Reloader) reload() error {
+	modTimes := make(map[string]time.Time)
+	for _, path := range r.files() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		modTimes[path] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2"},
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   r.clientAuth,
+	}
+
+	if r.opts.ClientCAFile != "" {
+		data, err := os.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return errors.New("client CA file contains no certificates")
+		}
+		config.ClientCAs = pool
+	}
+
+	r.mu.Lock()
+	r.config = config
+	r.modTimes = modTimes
+	r.checkedAt = time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// files возвращает файлы, за изменениями которых следит Reloader
+func (r *Reloader) files() []string {
+	files := []string{r.opts.CertFile, r.opts.KeyFile}
+	if r.opts.ClientCAFile != "" {
+		files = append(files, r.opts.ClientCAFile)
+	}
+	return files
+}
+
+// parseClientAuth преобразует режим проверки клиентов в tls.ClientAuthType
+func parseClientAuth(mode string) (tls.ClientAuthType, error) {
+	switch mode {
+	case "", "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "verify_if_given":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require":
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return 0, fmt.Errorf("unknown client auth mode %q", mode)
+	}
+}
@@ -0,0 +1,193 @@
+package logger
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// groupOrAttrs is either a group opened with WithGroup or attributes added with WithAttrs.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// handlerAttrs keeps the groups and attributes of a handler in the order they were added.
+type handlerAttrs []groupOrAttrs
+
+// withAttrs returns a copy of h with attrs added to the innermost open group.
+func (h handlerAttrs) withAttrs(attrs []slog.Attr) handlerAttrs {
+	if len(attrs) == 0 {
+		return h
+	}
+	return append(slices.Clip(h), groupOrAttrs{attrs: slices.Clone(attrs)})
+}
+
+// withGroup returns a copy of h with a new group that nests all later attributes.
+func (h handlerAttrs) withGroup(name string) handlerAttrs {
+	if name == "" {
+		return h
+	}
+	return append(slices.Clip(h), groupOrAttrs{group: name})
+}
+
+// resolve returns the handler attributes together with the attributes of record as a tree,
+// in which groups are nested slog.Group values. Values are resolved, empty attributes and
+// empty groups are removed and groups with an empty key are inlined, as slog.Handler requires.
+func (h handlerAttrs) resolve(record slog.Record) []slog.Attr {
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	attrs = resolveAttrs(attrs)
+
+	for i := len(h) - 1; i >= 0; i-- {
+		if h[i].group == "" {
+			attrs = append(resolveAttrs(h[i].attrs), attrs...)
+			continue
+		}
+		if len(attrs) == 0 {
+			continue
+		}
+		attrs = []slog.Attr{{Key: h[i].group, Value: slog.GroupValue(attrs...)}}
+	}
+	return attrs
+}
+
+// resolveAttrs resolves LogValuer values and normalizes groups recursively.
+func resolveAttrs(attrs []slog.Attr) []slog.Attr {
+	resolved := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		attr.Value = attr.Value.Resolve()
+		if attr.Value.Kind() != slog.KindGroup {
+			if !attr.Equal(slog.Attr{}) {
+				resolved = append(resolved, attr)
+			}
+			continue
+		}
+
+		group := resolveAttrs(attr.Value.Group())
+		switch {
+		case len(group) == 0:
+		case attr.Key == "":
+			resolved = append(resolved, group...)
+		default:
+			resolved = append(resolved, slog.Attr{Key: attr.Key, Value: slog.GroupValue(group...)})
+		}
+	}
+	return resolved
+}
+
+// jsonAttrs converts resolved attributes to a map in which groups become nested objects.
+func jsonAttrs(entry map[string]interface{}, attrs []slog.Attr) map[string]interface{} {
+	for _, attr := range attrs {
+		if attr.Value.Kind() == slog.KindGroup {
+			entry[attr.Key] = jsonAttrs(make(map[string]interface{}), attr.Value.Group())
+			continue
+		}
+		entry[attr.Key] = jsonValue(attr.Value)
+	}
+	return entry
+}
+
+// jsonValue converts a resolved attribute value to a value that encoding/json can marshal.
+func jsonValue(value slog.Value) interface{} {
+	switch value.Kind() {
+	case slog.KindDuration:
+		return value.Duration().String()
+	case slog.KindTime:
+		return value.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		switch v := value.Any().(type) {
+		case error:
+			return v.Error()
+		case json.Marshaler:
+			return v
+		case encoding.TextMarshaler:
+			return v
+		default:
+			if _, err := json.Marshal(v); err != nil {
+				return fmt.Sprintf("%+v", v)
+			}
+			return v
+		}
+	default:
+		return value.Any()
+	}
+}
+
+// formatLine formats a text log line "<level> - <time> - <message> key=value ...".
+// The time is omitted for records without one.
+func formatLine(level string, record slog.Record, timeLayout string, attrs []slog.Attr) []byte {
+	buf := make([]byte, 0, 128)
+	buf = append(buf, level...)
+	if !record.Time.IsZero() {
+		buf = append(buf, " - "...)
+		buf = record.Time.AppendFormat(buf, timeLayout)
+	}
+	buf = append(buf, " - "...)
+	buf = append(buf, record.Message...)
+	buf = appendText(buf, "", attrs)
+	return append(buf, '\n')
+}
+
+// appendText appends resolved attributes as space-separated key=value pairs.
+// Keys inside groups are qualified with the group names, e.g. request.method=GET.
+func appendText(buf []byte, prefix string, attrs []slog.Attr) []byte {
+	for _, attr := range attrs {
+		key := attr.Key
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if attr.Value.Kind() == slog.KindGroup {
+			buf = appendText(buf, key, attr.Value.Group())
+			continue
+		}
+		buf = append(buf, ' ')
+		buf = append(buf, key...)
+		buf = append(buf, '=')
+		buf = append(buf, textValue(attr.Value)...)
+	}
+	return buf
+}
+
+// textValue formats a resolved attribute value, quoting it when it would break key=value parsing.
+func textValue(value slog.Value) string {
+	var s string
+	switch value.Kind() {
+	case slog.KindString:
+		s = value.String()
+	case slog.KindTime:
+		s = value.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			s = err.Error()
+		} else {
+			s = fmt.Sprintf("%+v", value.Any())
+		}
+	default:
+		s = value.String()
+	}
+
+	if needsQuoting(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// needsQuoting reports whether s is empty or contains spaces, quotes, '=' or non-printable characters.
+func needsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	return strings.IndexFunc(s, func(r rune) bool {
+		return r == ' ' || r == '=' || r == '"' || !unicode.IsPrint(r)
+	}) >= 0
+}
@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// errConnecting is reported by Err until the first connection attempt completes.
+var errConnecting = errors.New("connecting")
+
+// attach sets the producer and releases the goroutines waiting for it. It returns false
+// without attaching if the handler is already closed, so that a producer is never attached
+// after Shutdown has stopped waiting for one.
+func (k *KafkaHandler) attach(producer sarama.AsyncProducer) bool {
+	k.attachMu.Lock()
+	defer k.attachMu.Unlock()
+
+	select {
+	case <-k.quitChan:
+		return false
+	default:
+	}
+	k.producer = producer
+	k.lastErr.Store(nil)
+	close(k.connected)
+	return true
+}
+
+// setNotConnected records why the handler has no producer yet, so that Err reports it.
+func (k *KafkaHandler) setNotConnected(cause error) {
+	err := fmt.Errorf("kafka is not connected: %w", cause)
+	k.lastErr.Store(&err)
+}
+
+// isConnected reports whether a producer is attached.
+func (k *KafkaHandler) isConnected() bool {
+	select {
+	case <-k.connected:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitConnected blocks until a producer is attached. It returns false if the handler is closed first.
+// A producer attached before the handler was closed is reported even if both have happened,
+// so that its results are still handled and it is drained on shutdown.
+func (k *KafkaHandler) waitConnected() bool {
+	select {
+	case <-k.connected:
+		return true
+	case <-k.quitChan:
+		return k.isConnected()
+	}
+}
+
+// connectLoop creates the producer, retrying with exponential backoff until it succeeds
+// or the handler is closed, and attaches it. Progress is reported on stderr, so that
+// stdout stays free for command output.
+func (k *KafkaHandler) connectLoop(brokers []string, opts KafkaOptions) {
+	defer k.wg.Done()
+
+	delay := opts.ReconnectBackoff
+	if delay <= 0 {
+		delay = time.Second
+	}
+	maxDelay := opts.ReconnectMaxBackoff
+	if maxDelay <= 0 {
+		maxDelay = time.Minute
+	}
+
+	for {
+		producer, err := sarama.NewAsyncProducer(brokers, k.saramaCfg)
+		if err == nil {
+			if !k.attach(producer) {
+				// The handler was closed while connecting
+				producer.Close()
+				return
+			}
+			fmt.Fprintf(os.Stderr, "connected to kafka, log records are sent to topic %s\n", k.topic)
+			return
+		}
+		k.setNotConnected(err)
+		fmt.Fprintf(os.Stderr, "kafka is unreachable, logging to the remaining sinks and retrying in %s: %v\n", delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-k.quitChan:
+			timer.Stop()
+			return
+		}
+
+		delay = min(delay*2, maxDelay)
+	}
+}
@@ -0,0 +1,239 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ContextExtractor returns the attributes that the values carried by ctx add to a record.
+type ContextExtractor func(ctx context.Context) []slog.Attr
+
+// namedExtractor is a registered ContextExtractor.
+type namedExtractor struct {
+	name    string
+	extract ContextExtractor
+}
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   = []namedExtractor{
+		{name: "request_id", extract: requestIDAttrs},
+		{name: "user_id", extract: userIDAttrs},
+		{name: "rpc_method", extract: rpcMethodAttrs},
+		{name: "trace", extract: traceAttrs},
+	}
+)
+
+// RegisterContextExtractor registers an extractor that every ContextHandler applies to each record.
+// Registering under an existing name replaces that extractor, a nil extractor removes it.
+// The request ID, user ID, RPC method and trace extractors are registered by default.
+func RegisterContextExtractor(name string, extractor ContextExtractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+
+	// The slice is copied rather than modified, so that handlers can use it without holding the lock
+	updated := make([]namedExtractor, 0, len(extractors)+1)
+	replaced := false
+	for _, registered := range extractors {
+		if registered.name != name {
+			updated = append(updated, registered)
+			continue
+		}
+		replaced = true
+		if extractor != nil {
+			updated = append(updated, namedExtractor{name: name, extract: extractor})
+		}
+	}
+	if !replaced && extractor != nil {
+		updated = append(updated, namedExtractor{name: name, extract: extractor})
+	}
+	extractors = updated
+}
+
+// contextAttrs returns the attributes of all registered extractors for ctx.
+func contextAttrs(ctx context.Context) []slog.Attr {
+	extractorsMu.RLock()
+	registered := extractors
+	extractorsMu.RUnlock()
+
+	var attrs []slog.Attr
+	for _, extractor := range registered {
+		attrs = append(attrs, extractor.extract(ctx)...)
+	}
+	return attrs
+}
+
+// requestIDKey is the context key for the request ID.
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the request ID, which ContextHandler adds to every record.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID carried by the context, if any.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDKey{}).(string)
+	return requestID, ok
+}
+
+// requestIDAttrs adds the request_id attribute.
+func requestIDAttrs(ctx context.Context) []slog.Attr {
+	if requestID, ok := RequestIDFromContext(ctx); ok {
+		return []slog.Attr{slog.String("request_id", requestID)}
+	}
+	return nil
+}
+
+// userIDKey is the context key for the authenticated user ID.
+type userIDKey struct{}
+
+// WithUserID returns a context carrying the ID of the authenticated user, which ContextHandler adds to every record.
+func WithUserID(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserIDFromContext returns the user ID carried by the context, if any.
+func UserIDFromContext(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(int64)
+	return userID, ok
+}
+
+// userIDAttrs adds the user_id attribute.
+func userIDAttrs(ctx context.Context) []slog.Attr {
+	if userID, ok := UserIDFromContext(ctx); ok {
+		return []slog.Attr{slog.Int64("user_id", userID)}
+	}
+	return nil
+}
+
+// rpcMethodKey is the context key for the RPC method.
+type rpcMethodKey struct{}
+
+// WithRPCMethod returns a context carrying the full name of the RPC method being served,
+// which ContextHandler adds to every record.
+func WithRPCMethod(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, rpcMethodKey{}, method)
+}
+
+// RPCMethodFromContext returns the RPC method carried by the context, if any.
+func RPCMethodFromContext(ctx context.Context) (string, bool) {
+	method, ok := ctx.Value(rpcMethodKey{}).(string)
+	return method, ok
+}
+
+// rpcMethodAttrs adds the rpc_method attribute.
+func rpcMethodAttrs(ctx context.Context) []slog.Attr {
+	if method, ok := RPCMethodFromContext(ctx); ok {
+		return []slog.Attr{slog.String("rpc_method", method)}
+	}
+	return nil
+}
+
+// traceAttrs adds the trace_id and span_id attributes of the active span.
+func traceAttrs(ctx context.Context) []slog.Attr {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return nil
+	}
+	return []slog.Attr{
+		slog.String("trace_id", spanContext.TraceID().String()),
+		slog.String("span_id", spanContext.SpanID().String()),
+	}
+}
+
+// ContextHandler adds values carried by the context to every record before passing it on,
+// using the extractors registered with RegisterContextExtractor. The attributes are added at
+// the top level even when groups are open. Attributes whose key the record or the handler
+// already has at the top level are skipped, so a value logged explicitly wins over the context.
+type ContextHandler struct {
+	root slog.Handler    // Wrapped handler with the attributes added before the first group
+	next slog.Handler    // root with the groups and attributes added after them
+	ops  handlerAttrs    // Groups and attributes applied to root to get next
+	keys map[string]bool // Keys of the attributes added to root
+}
+
+// NewContextHandler wraps next with a ContextHandler.
+func NewContextHandler(next slog.Handler) *ContextHandler {
+	return &ContextHandler{root: next, next: next}
+}
+
+// Enabled checks if the level is enabled for the wrapped handler.
+func (c *ContextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return c.next.Enabled(ctx, level)
+}
+
+// Handle adds the attributes extracted from ctx to the record and passes it to the wrapped handler.
+func (c *ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := contextAttrs(ctx)
+	if len(attrs) == 0 {
+		return c.next.Handle(ctx, record)
+	}
+
+	// Attributes of the record are nested in the open groups and do not clash with top-level ones
+	present := make(map[string]bool, record.NumAttrs())
+	if len(c.ops) == 0 {
+		record.Attrs(func(attr slog.Attr) bool {
+			present[attr.Key] = true
+			return true
+		})
+	}
+	added := attrs[:0]
+	for _, attr := range attrs {
+		if !present[attr.Key] && !c.keys[attr.Key] {
+			added = append(added, attr)
+		}
+	}
+	if len(added) == 0 {
+		return c.next.Handle(ctx, record)
+	}
+
+	if len(c.ops) == 0 {
+		record = record.Clone()
+		record.AddAttrs(added...)
+		return c.next.Handle(ctx, record)
+	}
+
+	// The attributes go to the top level, followed by the open groups resolved into the record,
+	// so root handles it without a handler chain rebuilt for every record
+	resolved := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	resolved.AddAttrs(added...)
+	resolved.AddAttrs(c.ops.resolve(record)...)
+	return c.root.Handle(ctx, resolved)
+}
+
+// WithAttrs adds attributes to the wrapped handler.
+func (c *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return c
+	}
+	if len(c.ops) > 0 {
+		return &ContextHandler{root: c.root, next: c.next.WithAttrs(attrs), ops: c.ops.withAttrs(attrs), keys: c.keys}
+	}
+
+	keys := make(map[string]bool, len(c.keys)+len(attrs))
+	for key := range c.keys {
+		keys[key] = true
+	}
+	for _, attr := range attrs {
+		keys[attr.Key] = true
+	}
+	root := c.root.WithAttrs(attrs)
+	return &ContextHandler{root: root, next: root, keys: keys}
+}
+
+// WithGroup adds a group to the wrapped handler.
+func (c *ContextHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return c
+	}
+	return &ContextHandler{root: c.root, next: c.next.WithGroup(name), ops: c.ops.withGroup(name), keys: c.keys}
+}
+
+// Unwrap returns the wrapped handler.
+func (c *ContextHandler) Unwrap() slog.Handler {
+	return c.next
+}
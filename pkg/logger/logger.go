@@ -3,13 +3,22 @@ package logger
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"log/slog"
 )
 
@@ -22,43 +31,170 @@ const (
 	ColorBlue   = "\033[34m"
 )
 
-// KafkaHandler sends logs to Kafka topic asynchronously.
+// DefaultCloseTimeout bounds how long Close waits for buffered records to be delivered.
+const DefaultCloseTimeout = 5 * time.Second
+
+// flushPollInterval is how often Flush checks whether Kafka has acknowledged the sent records.
+const flushPollInterval = 10 * time.Millisecond
+
+// AllHandlers is the name that SetHandlerLevel and Levels use for the minimum level of the MultiHandler itself.
+const AllHandlers = "all"
+
+// newLevelVar returns a level variable that passes records of all levels.
+func newLevelVar() *slog.LevelVar {
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelDebug)
+	return level
+}
+
+// HandlerStats describes the buffer state of an asynchronous handler.
+type HandlerStats struct {
+	Name         string // Handler name, e.g. "kafka" or "file"
+	Depth        int    // Records waiting in the buffer
+	Capacity     int    // Buffer capacity
+	Dropped      uint64 // Records dropped because the buffer was full
+	Spooled      uint64 // Records stored in the disk spool because they could not be delivered
+	Replayed     uint64 // Spooled records delivered later
+	SpoolErrors  uint64 // Failed reads, replays and writes of the disk spool
+	Sampled      uint64 // Records dropped by sampling
+	Deduplicated uint64 // Repeated records collapsed into a "repeated N times" summary
+}
+
+// kafkaRecord is a log record queued for Kafka together with its resolved attributes,
+// the trace context headers of its caller and the ID of the active trace.
+type kafkaRecord struct {
+	record  slog.Record
+	attrs   []slog.Attr
+	headers []sarama.RecordHeader
+	traceID string
+}
+
+// KafkaHandler sends logs to Kafka topic asynchronously as JSON objects,
+// with attribute groups rendered as nested objects.
 type KafkaHandler struct {
+	*kafkaSink
+	attrs handlerAttrs
+}
+
+// kafkaSink is the producer and the buffer shared by a KafkaHandler and the handlers derived from it.
+// The producer is set once, before connected is closed.
+type kafkaSink struct {
 	producer  sarama.AsyncProducer
+	connected chan struct{}
 	topic     string
-	logChan   chan slog.Record
+	routes    []TopicRoute
+	service   string
+	logChan   chan kafkaRecord
+	flushChan chan chan struct{}
+	pending   atomic.Int64 // Records sent to the producer and not yet acknowledged
 	wg        sync.WaitGroup
+	resultsWg sync.WaitGroup
 	quitChan  chan struct{}
+	closeOnce sync.Once
+	attachMu  sync.Mutex // Orders attaching the producer against closing quitChan
 	saramaCfg *sarama.Config
+	lastErr   atomic.Pointer[error]
+	dropped   atomic.Uint64
+	level     *slog.LevelVar
+	spool     *diskSpool
+	spooled   atomic.Uint64
+	replayed  atomic.Uint64
+	spoolErrs atomic.Uint64
+	queue     QueueOptions
+	filter    *recordFilter
 }
 
-// NewKafkaHandler initializes a new KafkaHandler.
-func NewKafkaHandler(brokers []string, topic string, bufferSize int) (*KafkaHandler, error) {
-	config := sarama.NewConfig()
-	config.Producer.RequiredAcks = sarama.WaitForAll
-	config.Producer.Retry.Max = 5
-	config.Producer.Return.Successes = false
-	config.Producer.Return.Errors = true
-	config.Producer.Partitioner = sarama.NewHashPartitioner
+// KafkaOptions configures a KafkaHandler.
+type KafkaOptions struct {
+	ServiceName         string          // Sent in the "service" header and used as the message key of records without a trace
+	Routes              []TopicRoute    // Records go to the topic of the matching route with the highest MinLevel, others to the handler topic
+	Producer            ProducerOptions // Security, compression, batching and acks of the producer
+	Queue               QueueOptions    // Buffer overflow policy, sampling and deduplication
+	Spool               SpoolOptions    // Disk spool for records that overflow the buffer or fail delivery
+	ReconnectBackoff    time.Duration   // First delay between background connection attempts. Defaults to 1s
+	ReconnectMaxBackoff time.Duration   // Maximum delay between background connection attempts. Defaults to 1m
+}
 
-	producer, err := sarama.NewAsyncProducer(brokers, config)
+// NewKafkaHandler initializes a new KafkaHandler and fails if the brokers are unreachable.
+// With a spool directory, records that overflow the buffer or fail delivery are stored on disk
+// and replayed in order once Kafka is reachable again.
+func NewKafkaHandler(brokers []string, topic string, bufferSize int, opts KafkaOptions) (*KafkaHandler, error) {
+	handler, err := newKafkaHandler(topic, bufferSize, opts)
 	if err != nil {
+		return nil, err
+	}
+
+	producer, err := sarama.NewAsyncProducer(brokers, handler.saramaCfg)
+	if err != nil {
+		handler.Close()
 		return nil, fmt.Errorf("failed to create async producer: %w", err)
 	}
+	handler.attach(producer)
+	return handler, nil
+}
 
-	handler := &KafkaHandler{
-		producer:  producer,
+// StartKafkaHandler initializes a KafkaHandler that does not need the brokers to be reachable.
+// The handler connects in the background and retries with exponential backoff; until it connects,
+// records wait in the buffer or go to the spool, and Err reports that Kafka is not connected.
+func StartKafkaHandler(brokers []string, topic string, bufferSize int, opts KafkaOptions) (*KafkaHandler, error) {
+	handler, err := newKafkaHandler(topic, bufferSize, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	handler.setNotConnected(errConnecting)
+	handler.wg.Add(1)
+	go handler.connectLoop(brokers, opts)
+	return handler, nil
+}
+
+// newKafkaHandler creates a KafkaHandler without a producer and starts its goroutines,
+// which wait until a producer is attached.
+func newKafkaHandler(topic string, bufferSize int, opts KafkaOptions) (*KafkaHandler, error) {
+	config, err := newSaramaConfig(opts.Producer)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := slices.Clone(opts.Routes)
+	sort.SliceStable(routes, func(i, j int) bool { return routes[i].MinLevel > routes[j].MinLevel })
+
+	var spool *diskSpool
+	if opts.Spool.Dir != "" {
+		if spool, err = openSpool(opts.Spool); err != nil {
+			return nil, err
+		}
+	}
+
+	handler := &KafkaHandler{kafkaSink: &kafkaSink{
+		connected: make(chan struct{}),
 		topic:     topic,
-		logChan:   make(chan slog.Record, bufferSize),
+		routes:    routes,
+		service:   opts.ServiceName,
+		logChan:   make(chan kafkaRecord, bufferSize),
+		flushChan: make(chan chan struct{}),
 		quitChan:  make(chan struct{}),
 		saramaCfg: config,
-	}
+		level:     newLevelVar(),
+		spool:     spool,
+		queue:     opts.Queue,
+		filter:    newRecordFilter(opts.Queue),
+	}}
 
 	handler.wg.Add(1)
 	go handler.processLogs()
 
-	handler.wg.Add(1)
-	go handler.handleProducerErrors()
+	handler.resultsWg.Add(1)
+	go handler.handleProducerResults()
+
+	if spool != nil {
+		interval := opts.Spool.ReplayInterval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		handler.wg.Add(1)
+		go handler.replayLoop(interval)
+	}
 
 	return handler, nil
 }
@@ -66,113 +202,399 @@ func NewKafkaHandler(brokers []string, topic string, bufferSize int) (*KafkaHand
 // processLogs sends logs into channel for asynchronous processing.
 func (k *KafkaHandler) processLogs() {
 	defer k.wg.Done()
+	if !k.waitConnected() {
+		return
+	}
 	for {
 		select {
-		case record := <-k.logChan:
-			logEntry := map[string]interface{}{
-				"time":  record.Time.Format(time.RFC3339),
-				"level": record.Level.String(),
-				"msg":   record.Message,
-			}
-			payload, err := json.Marshal(logEntry)
-			if err != nil {
-				fmt.Printf("failed to marshal log entry: %v\n", err)
-				continue
+		case queued := <-k.logChan:
+			k.send(queued)
+		case done := <-k.flushChan:
+			// Everything buffered before the flush request is handed to the producer before replying
+			for drained := false; !drained; {
+				select {
+				case queued := <-k.logChan:
+					k.send(queued)
+				default:
+					drained = true
+				}
 			}
+			close(done)
+		case <-k.quitChan:
+			return
+		}
+	}
+}
 
-			message := &sarama.ProducerMessage{
-				Topic: k.topic,
-				Key:   sarama.StringEncoder("log"),
-				Value: sarama.ByteEncoder(payload),
-			}
+// send hands a queued record to the producer. If the handler is closed while the producer
+// is not accepting messages, the record goes to the spool instead.
+func (k *KafkaHandler) send(queued kafkaRecord) {
+	message, err := k.message(queued)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to marshal log entry: %v\n", err)
+		return
+	}
+
+	k.pending.Add(1)
+	select {
+	case k.producer.Input() <- message:
+	case <-k.quitChan:
+		k.pending.Add(-1)
+		k.spoolMessage(message)
+	}
+}
 
-			k.producer.Input() <- message
+// message encodes a queued record as a Kafka message.
+func (k *KafkaHandler) message(queued kafkaRecord) (*sarama.ProducerMessage, error) {
+	record := queued.record
+	logEntry := jsonAttrs(map[string]interface{}{
+		"level": record.Level.String(),
+		"msg":   record.Message,
+	}, queued.attrs)
+	if !record.Time.IsZero() {
+		logEntry["time"] = record.Time.Format(time.RFC3339)
+	}
+	payload, err := json.Marshal(logEntry)
+	if err != nil {
+		return nil, err
+	}
 
-		case <-k.quitChan:
-			return
+	// Records of one trace share a key and therefore a partition, so they stay in order
+	key := queued.traceID
+	if key == "" {
+		key = k.service
+	}
+	if key == "" {
+		key = "log"
+	}
+
+	headers := slices.Clip(queued.headers)
+	if k.service != "" {
+		headers = append(headers, sarama.RecordHeader{Key: []byte("service"), Value: []byte(k.service)})
+	}
+	if queued.traceID != "" {
+		headers = append(headers, sarama.RecordHeader{Key: []byte("trace_id"), Value: []byte(queued.traceID)})
+	}
+
+	return &sarama.ProducerMessage{
+		Topic:   k.route(record.Level),
+		Key:     sarama.StringEncoder(key),
+		Value:   sarama.ByteEncoder(payload),
+		Headers: headers,
+	}, nil
+}
+
+// route returns the topic for records of the level.
+func (k *KafkaHandler) route(level slog.Level) string {
+	for _, route := range k.routes {
+		if level >= route.MinLevel {
+			return route.Topic
 		}
 	}
+	return k.topic
 }
 
-// handleProducerErrors processes producer errors.
-func (k *KafkaHandler) handleProducerErrors() {
-	defer k.wg.Done()
-	for {
+// handleProducerResults processes producer successes and errors and tracks the delivery state.
+// It runs until the producer is closed, so that the results of records still in flight
+// at shutdown are handled too.
+func (k *KafkaHandler) handleProducerResults() {
+	defer k.resultsWg.Done()
+	if !k.waitConnected() {
+		return
+	}
+	successes, errs := k.producer.Successes(), k.producer.Errors()
+	for successes != nil || errs != nil {
 		select {
-		case err, ok := <-k.producer.Errors():
+		case message, ok := <-successes:
 			if !ok {
-				return
+				successes = nil
+				continue
 			}
-			fmt.Printf("failed to write message to kafka: %v\n", err)
-		case <-k.quitChan:
-			return
+			k.lastErr.Store(nil)
+			if batch, ok := message.Metadata.(*replayBatch); ok {
+				batch.results <- nil
+				continue
+			}
+			k.pending.Add(-1)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			var deliveryErr error = err
+			k.lastErr.Store(&deliveryErr)
+			// Replayed messages stay in their spool segment until the whole segment is delivered
+			if batch, ok := err.Msg.Metadata.(*replayBatch); ok {
+				batch.results <- err
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "failed to write message to kafka: %v\n", err)
+			k.spoolMessage(err.Msg)
+			k.pending.Add(-1)
 		}
 	}
 }
 
-// Enabled checks if the level is enabled.
+// Err returns the error of the last failed delivery, or nil if the last delivery succeeded.
+func (k *KafkaHandler) Err() error {
+	if err := k.lastErr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// Name returns the name of the handler used in stats and level control.
+func (k *KafkaHandler) Name() string {
+	return "kafka"
+}
+
+// SetLevel changes the minimum level of the handler at runtime; by default all levels are passed.
+func (k *KafkaHandler) SetLevel(level slog.Level) {
+	k.level.Set(level)
+}
+
+// Level returns the minimum level of the handler.
+func (k *KafkaHandler) Level() slog.Level {
+	return k.level.Level()
+}
+
+// Enabled reports whether the level is at or above the minimum level of the handler.
 func (k *KafkaHandler) Enabled(ctx context.Context, level slog.Level) bool {
-	return true
+	return level >= k.level.Level()
 }
 
-// Handle sends logs into a channel for asynchronous processing.
+// Handle sends logs into a channel for asynchronous processing after sampling and deduplication.
+// The trace context of ctx is propagated into the Kafka message headers.
 func (k *KafkaHandler) Handle(ctx context.Context, record slog.Record) error {
-	select {
-	case k.logChan <- record:
-		return nil
-	default:
-		fmt.Println("log channel is full, dropping log message")
+	headers := traceHeaders(ctx)
+	var traceID string
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		traceID = spanContext.TraceID().String()
+	}
+	for _, filtered := range k.filter.apply(record, k.attrs.resolve(record)) {
+		k.enqueue(kafkaRecord{record: filtered.record, attrs: filtered.attrs, headers: headers, traceID: traceID}, k.queue)
+	}
+	return nil
+}
+
+// enqueue buffers a record according to the overflow policy of opts. Records that do not fit
+// go to the spool, or are counted as dropped without a spool.
+func (k *KafkaHandler) enqueue(queued kafkaRecord, opts QueueOptions) {
+	for _, rejected := range offer(k.logChan, queued, opts, k.quitChan) {
+		if k.spool == nil {
+			k.dropped.Add(1)
+			continue
+		}
+		message, err := k.message(rejected)
+		if err != nil {
+			k.dropped.Add(1)
+			continue
+		}
+		k.spoolMessage(message)
+	}
+}
+
+// enqueuePending buffers the summaries of repeats not reported yet without blocking.
+func (k *KafkaHandler) enqueuePending() {
+	for _, summary := range k.filter.pending() {
+		k.enqueue(kafkaRecord{record: summary.record, attrs: summary.attrs}, QueueOptions{Overflow: DropNewest})
+	}
+}
+
+// traceHeaders encodes the trace context of ctx as Kafka record headers using the global propagator.
+func traceHeaders(ctx context.Context) []sarama.RecordHeader {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
 		return nil
 	}
+
+	headers := make([]sarama.RecordHeader, 0, len(carrier))
+	for key, value := range carrier {
+		headers = append(headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+	}
+	return headers
+}
+
+// Stats returns the buffer state of the handler.
+func (k *KafkaHandler) Stats() HandlerStats {
+	sampled, deduplicated := k.filter.stats()
+	return HandlerStats{
+		Name:         k.Name(),
+		Depth:        len(k.logChan),
+		Capacity:     cap(k.logChan),
+		Dropped:      k.dropped.Load(),
+		Spooled:      k.spooled.Load(),
+		Replayed:     k.replayed.Load(),
+		SpoolErrors:  k.spoolErrs.Load(),
+		Sampled:      sampled,
+		Deduplicated: deduplicated,
+	}
 }
 
-// WithAttrs adds attributes to the handler.
+// WithAttrs returns a handler that adds attrs to every record and shares the producer with k.
 func (k *KafkaHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return k
+	return &KafkaHandler{kafkaSink: k.kafkaSink, attrs: k.attrs.withAttrs(attrs)}
 }
 
-// WithGroup adds a group to the handler.
+// WithGroup returns a handler that nests later attributes in the group name and shares the producer with k.
 func (k *KafkaHandler) WithGroup(name string) slog.Handler {
-	return k
+	return &KafkaHandler{kafkaSink: k.kafkaSink, attrs: k.attrs.withGroup(name)}
 }
 
-// Close gracefully shuts down KafkaHandler.
-func (k *KafkaHandler) Close() error {
-	close(k.quitChan)
-	k.wg.Wait()
-	if err := k.producer.Close(); err != nil {
-		return fmt.Errorf("failed to close producer: %w", err)
+// Flush waits until the records buffered before the call are acknowledged by Kafka or
+// stored in the spool, or until ctx is done. While Kafka is not connected, buffered records
+// are moved to the spool; without a spool Flush reports that Kafka is not connected.
+func (k *KafkaHandler) Flush(ctx context.Context) error {
+	k.enqueuePending()
+	if !k.isConnected() {
+		return k.spoolBuffered()
+	}
+
+	done := make(chan struct{})
+	select {
+	case k.flushChan <- done:
+	case <-k.quitChan:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush kafka log records: %w", ctx.Err())
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush kafka log records: %w", ctx.Err())
+	}
+
+	ticker := time.NewTicker(flushPollInterval)
+	defer ticker.Stop()
+	for k.pending.Load() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("failed to flush kafka log records, %d not acknowledged: %w", k.pending.Load(), ctx.Err())
+		}
 	}
 	return nil
 }
 
-// FileHandler saves logs to a file asynchronously.
+// spoolBuffered moves the buffered records to the spool, or reports that Kafka
+// is not connected if there is no spool to keep them.
+func (k *KafkaHandler) spoolBuffered() error {
+	if k.spool == nil {
+		if len(k.logChan) == 0 {
+			return nil
+		}
+		return fmt.Errorf("failed to flush %d kafka log records: %w", len(k.logChan), k.Err())
+	}
+	for {
+		select {
+		case queued := <-k.logChan:
+			message, err := k.message(queued)
+			if err != nil {
+				k.dropped.Add(1)
+				continue
+			}
+			k.spoolMessage(message)
+		default:
+			return nil
+		}
+	}
+}
+
+// Close drains the buffer and shuts down KafkaHandler, waiting at most DefaultCloseTimeout.
+func (k *KafkaHandler) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCloseTimeout)
+	defer cancel()
+	return k.Shutdown(ctx)
+}
+
+// Shutdown flushes the buffered records and shuts down KafkaHandler. Records that are not
+// delivered before ctx is done go to the spool, or are counted as dropped without a spool.
+// Repeated calls return nil.
+func (k *KafkaHandler) Shutdown(ctx context.Context) error {
+	var errs []error
+	k.closeOnce.Do(func() {
+		if err := k.Flush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+
+		k.attachMu.Lock()
+		close(k.quitChan)
+		k.attachMu.Unlock()
+		if err := waitGroup(ctx, &k.wg); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop kafka log handler: %w", err))
+		}
+		if k.spool != nil {
+			k.spoolBuffered()
+		} else {
+			k.dropped.Add(uint64(len(k.logChan)))
+		}
+
+		if k.isConnected() {
+			// Closing the producer delivers the records in flight; their results are still handled
+			k.producer.AsyncClose()
+		}
+		if err := waitGroup(ctx, &k.resultsWg); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close producer: %w", err))
+		}
+
+		if k.spool != nil {
+			if err := k.spool.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// fileRecord is a log record queued for the file together with its resolved attributes.
+type fileRecord struct {
+	record slog.Record
+	attrs  []slog.Attr
+}
+
+// FileHandler saves logs to a file asynchronously as text lines with key=value attributes.
 type FileHandler struct {
-	file     *os.File
-	logChan  chan slog.Record
-	wg       sync.WaitGroup
-	quitChan chan struct{}
+	*fileSink
+	attrs handlerAttrs
 }
 
-// NewFileHandler initializes a new FileHandler.
-func NewFileHandler(serviceName string, bufferSize int) (*FileHandler, error) {
-	logDir := filepath.Join("logs", serviceName)
-	err := os.MkdirAll(logDir, os.ModePerm)
-	if err != nil {
-		return nil, err
+// fileSink is the file and the buffer shared by a FileHandler and the handlers derived from it.
+type fileSink struct {
+	file      *rotatingFile
+	logChan   chan fileRecord
+	flushChan chan chan struct{}
+	wg        sync.WaitGroup
+	quitChan  chan struct{}
+	closeOnce sync.Once
+	dropped   atomic.Uint64
+	level     *slog.LevelVar
+	queue     QueueOptions
+	filter    *recordFilter
+}
+
+// NewFileHandler initializes a new FileHandler writing to <opts.Dir>/<serviceName>/app.log.
+// The file is rotated and cleaned up according to opts and reopened on SIGHUP,
+// so it also works with an external logrotate.
+func NewFileHandler(serviceName string, bufferSize int, opts FileOptions) (*FileHandler, error) {
+	if opts.Dir == "" {
+		opts.Dir = "logs"
 	}
 
-	logFilePath := filepath.Join(logDir, "app.log")
-	file, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	file, err := openRotatingFile(filepath.Join(opts.Dir, serviceName, "app.log"), opts)
 	if err != nil {
 		return nil, err
 	}
 
-	handler := &FileHandler{
-		file:     file,
-		logChan:  make(chan slog.Record, bufferSize),
-		quitChan: make(chan struct{}),
-	}
+	handler := &FileHandler{fileSink: &fileSink{
+		file:      file,
+		logChan:   make(chan fileRecord, bufferSize),
+		flushChan: make(chan chan struct{}),
+		quitChan:  make(chan struct{}),
+		level:     newLevelVar(),
+		queue:     opts.Queue,
+		filter:    newRecordFilter(opts.Queue),
+	}}
 
 	handler.wg.Add(1)
 	go handler.processLogs()
@@ -183,65 +605,212 @@ func NewFileHandler(serviceName string, bufferSize int) (*FileHandler, error) {
 // processLogs reads log records from a channel and writes them to the file.
 func (f *FileHandler) processLogs() {
 	defer f.wg.Done()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
 	for {
 		select {
-		case record := <-f.logChan:
-			line := fmt.Sprintf("[%s] - %s - %s", record.Level.String(), record.Time.Format(time.RFC3339), record.Message)
-			f.file.Write(append([]byte(line), '\n'))
+		case <-hup:
+			if err := f.file.Reopen(); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to reopen log file: %v\n", err)
+			}
+		case queued := <-f.logChan:
+			f.write(queued)
+		case done := <-f.flushChan:
+			f.drain()
+			if err := f.file.Sync(); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to sync log file: %v\n", err)
+			}
+			close(done)
 		case <-f.quitChan:
+			f.drain()
+			return
+		}
+	}
+}
+
+// write appends a queued record to the file.
+func (f *FileHandler) write(queued fileRecord) {
+	f.file.Write(formatLine("["+queued.record.Level.String()+"]", queued.record, time.RFC3339, queued.attrs))
+}
+
+// drain writes the records that are already buffered.
+func (f *FileHandler) drain() {
+	for {
+		select {
+		case queued := <-f.logChan:
+			f.write(queued)
+		default:
 			return
 		}
 	}
 }
 
-// Enabled checks if the level is enabled.
+// Name returns the name of the handler used in stats and level control.
+func (f *FileHandler) Name() string {
+	return "file"
+}
+
+// SetLevel changes the minimum level of the handler at runtime; by default all levels are passed.
+func (f *FileHandler) SetLevel(level slog.Level) {
+	f.level.Set(level)
+}
+
+// Level returns the minimum level of the handler.
+func (f *FileHandler) Level() slog.Level {
+	return f.level.Level()
+}
+
+// Enabled reports whether the level is at or above the minimum level of the handler.
 func (f *FileHandler) Enabled(ctx context.Context, level slog.Level) bool {
-	return true
+	return level >= f.level.Level()
 }
 
-// Handle sends logs into a channel for asynchronous processing.
+// Handle sends logs into a channel for asynchronous processing after sampling and deduplication.
 func (f *FileHandler) Handle(ctx context.Context, record slog.Record) error {
-	select {
-	case f.logChan <- record:
-		return nil
-	default:
-		fmt.Println("file log channel is full, dropping log message")
-		return nil
+	for _, filtered := range f.filter.apply(record, f.attrs.resolve(record)) {
+		rejected := offer(f.logChan, fileRecord{record: filtered.record, attrs: filtered.attrs}, f.queue, f.quitChan)
+		f.dropped.Add(uint64(len(rejected)))
+	}
+	return nil
+}
+
+// enqueuePending buffers the summaries of repeats not reported yet without blocking.
+func (f *FileHandler) enqueuePending() {
+	for _, summary := range f.filter.pending() {
+		rejected := offer(f.logChan, fileRecord{record: summary.record, attrs: summary.attrs}, QueueOptions{Overflow: DropNewest}, f.quitChan)
+		f.dropped.Add(uint64(len(rejected)))
+	}
+}
+
+// Stats returns the buffer state of the handler.
+func (f *FileHandler) Stats() HandlerStats {
+	sampled, deduplicated := f.filter.stats()
+	return HandlerStats{
+		Name:         f.Name(),
+		Depth:        len(f.logChan),
+		Capacity:     cap(f.logChan),
+		Dropped:      f.dropped.Load(),
+		Sampled:      sampled,
+		Deduplicated: deduplicated,
 	}
 }
 
-// WithAttrs adds attributes to the handler.
+// WithAttrs returns a handler that adds attrs to every record and shares the file with f.
 func (f *FileHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return f
+	return &FileHandler{fileSink: f.fileSink, attrs: f.attrs.withAttrs(attrs)}
 }
 
-// WithGroup adds a group to the handler.
+// WithGroup returns a handler that nests later attributes in the group name and shares the file with f.
 func (f *FileHandler) WithGroup(name string) slog.Handler {
-	return f
+	return &FileHandler{fileSink: f.fileSink, attrs: f.attrs.withGroup(name)}
 }
 
-// Close gracefully shuts down FileHandler.
+// Flush waits until the records buffered before the call are written and synced to disk,
+// or until ctx is done.
+func (f *FileHandler) Flush(ctx context.Context) error {
+	f.enqueuePending()
+	done := make(chan struct{})
+	select {
+	case f.flushChan <- done:
+	case <-f.quitChan:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush log file: %w", ctx.Err())
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush log file: %w", ctx.Err())
+	}
+}
+
+// Close drains the buffer and shuts down FileHandler, waiting at most DefaultCloseTimeout.
 func (f *FileHandler) Close() error {
-	close(f.quitChan)
-	f.wg.Wait()
-	return f.file.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCloseTimeout)
+	defer cancel()
+	return f.Shutdown(ctx)
+}
+
+// Shutdown writes the buffered records and closes the file. If ctx is done first,
+// the records are still being written in the background and the file is left open.
+// Repeated calls return nil.
+func (f *FileHandler) Shutdown(ctx context.Context) error {
+	var err error
+	f.closeOnce.Do(func() {
+		f.enqueuePending()
+		close(f.quitChan)
+		if err = waitGroup(ctx, &f.wg); err != nil {
+			err = fmt.Errorf("failed to write buffered log records: %w", err)
+			return
+		}
+		err = f.file.Close()
+	})
+	return err
+}
+
+// waitGroup waits for wg until ctx is done.
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // StdoutHandler sends logs to stdout with colored text synchronously.
 type StdoutHandler struct {
+	name   string
 	writer *os.File
+	level  *slog.LevelVar
+	attrs  handlerAttrs
 }
 
 // NewStdoutHandler initializes a new StdoutHandler.
 func NewStdoutHandler() *StdoutHandler {
 	return &StdoutHandler{
+		name:   "stdout",
 		writer: os.Stdout,
+		level:  newLevelVar(),
+	}
+}
+
+// NewStderrHandler initializes a StdoutHandler that writes to stderr, keeping stdout free for command output.
+func NewStderrHandler() *StdoutHandler {
+	return &StdoutHandler{
+		name:   "stderr",
+		writer: os.Stderr,
+		level:  newLevelVar(),
 	}
 }
 
-// Enabled checks if the level is enabled.
+// Name returns the name of the handler used in level control: stdout or stderr.
+func (s *StdoutHandler) Name() string {
+	return s.name
+}
+
+// SetLevel changes the minimum level of the handler at runtime; by default all levels are passed.
+func (s *StdoutHandler) SetLevel(level slog.Level) {
+	s.level.Set(level)
+}
+
+// Level returns the minimum level of the handler.
+func (s *StdoutHandler) Level() slog.Level {
+	return s.level.Level()
+}
+
+// Enabled reports whether the level is at or above the minimum level of the handler.
 func (s *StdoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
-	return true
+	return level >= s.level.Level()
 }
 
 // Handle processes and outputs the log record to stdout with colors synchronously.
@@ -259,25 +828,25 @@ func (s *StdoutHandler) Handle(ctx context.Context, record slog.Record) error {
 	default:
 		color = ColorReset
 	}
-	line := fmt.Sprintf("%s[%s]%s - %s - %s\n",
-		color,
-		record.Level.String(),
-		ColorReset,
-		record.Time.Format("2006-01-02 15:04:05"),
-		record.Message,
-	)
-	_, err := s.writer.Write([]byte(line))
+	level := color + "[" + record.Level.String() + "]" + ColorReset
+	line := formatLine(level, record, "2006-01-02 15:04:05", s.attrs.resolve(record))
+	_, err := s.writer.Write(line)
 	return err
 }
 
-// WithAttrs adds attributes to the handler.
+// WithAttrs returns a handler that adds attrs to every record.
 func (s *StdoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return s
+	return &StdoutHandler{name: s.name, writer: s.writer, level: s.level, attrs: s.attrs.withAttrs(attrs)}
 }
 
-// WithGroup adds a group to the handler.
+// WithGroup returns a handler that nests later attributes in the group name.
 func (s *StdoutHandler) WithGroup(name string) slog.Handler {
-	return s
+	return &StdoutHandler{name: s.name, writer: s.writer, level: s.level, attrs: s.attrs.withGroup(name)}
+}
+
+// Flush is a no-op for synchronous handler.
+func (s *StdoutHandler) Flush(ctx context.Context) error {
+	return nil
 }
 
 // Close is a no-op for synchronous handler.
@@ -286,19 +855,70 @@ func (s *StdoutHandler) Close() error {
 }
 
 // MultiHandler combines multiple handlers.
+// Records below the minimum level are dropped before they reach any handler.
 type MultiHandler struct {
 	handlers []slog.Handler
+	level    *slog.LevelVar
 }
 
-// NewMultiHandler initializes a new MultiHandler.
+// NewMultiHandler initializes a new MultiHandler that passes records of all levels.
 func NewMultiHandler(handlers ...slog.Handler) *MultiHandler {
 	return &MultiHandler{
 		handlers: handlers,
+		level:    newLevelVar(),
+	}
+}
+
+// levelHandler is a handler with a named minimum level that can be changed at runtime.
+type levelHandler interface {
+	Name() string
+	Level() slog.Level
+	SetLevel(level slog.Level)
+}
+
+// Levels returns the minimum level of every handler with level control by name,
+// and the minimum level of the MultiHandler itself under AllHandlers.
+func (m *MultiHandler) Levels() map[string]slog.Level {
+	levels := map[string]slog.Level{AllHandlers: m.level.Level()}
+	for _, h := range m.handlers {
+		if lh, ok := h.(levelHandler); ok {
+			levels[lh.Name()] = lh.Level()
+		}
 	}
+	return levels
+}
+
+// SetHandlerLevel changes the minimum level of the handler with the given name,
+// or of the MultiHandler itself for AllHandlers.
+func (m *MultiHandler) SetHandlerLevel(name string, level slog.Level) error {
+	if name == AllHandlers {
+		m.SetLevel(level)
+		return nil
+	}
+	for _, h := range m.handlers {
+		if lh, ok := h.(levelHandler); ok && lh.Name() == name {
+			lh.SetLevel(level)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown log handler %q", name)
+}
+
+// SetLevel changes the minimum level at runtime, including for handlers derived with WithAttrs and WithGroup.
+func (m *MultiHandler) SetLevel(level slog.Level) {
+	m.level.Set(level)
+}
+
+// Level returns the current minimum level.
+func (m *MultiHandler) Level() slog.Level {
+	return m.level.Level()
 }
 
 // Enabled checks if the level is enabled for any handler.
 func (m *MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if level < m.level.Level() {
+		return false
+	}
 	for _, h := range m.handlers {
 		if h.Enabled(ctx, level) {
 			return true
@@ -307,10 +927,13 @@ func (m *MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
 	return false
 }
 
-// Handle adds the record to all handlers.
+// Handle adds the record to all handlers that are enabled for its level.
 func (m *MultiHandler) Handle(ctx context.Context, record slog.Record) error {
 	var firstErr error
 	for _, h := range m.handlers {
+		if !h.Enabled(ctx, record.Level) {
+			continue
+		}
 		if err := h.Handle(ctx, record); err != nil && firstErr == nil {
 			firstErr = err
 		}
@@ -324,7 +947,7 @@ func (m *MultiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	for i, h := range m.handlers {
 		handlers[i] = h.WithAttrs(attrs)
 	}
-	return NewMultiHandler(handlers...)
+	return &MultiHandler{handlers: handlers, level: m.level}
 }
 
 // WithGroup adds a group to all handlers.
@@ -333,36 +956,138 @@ func (m *MultiHandler) WithGroup(name string) slog.Handler {
 	for i, h := range m.handlers {
 		handlers[i] = h.WithGroup(name)
 	}
-	return NewMultiHandler(handlers...)
+	return &MultiHandler{handlers: handlers, level: m.level}
 }
 
-// CloseAll closes all handlers that implement the Close method.
-func (m *MultiHandler) CloseAll() {
+// Err returns the first error reported by handlers that track the state of their sink.
+func (m *MultiHandler) Err() error {
 	for _, h := range m.handlers {
-		if closer, ok := h.(interface{ Close() error }); ok {
-			closer.Close()
+		if reporter, ok := h.(interface{ Err() error }); ok {
+			if err := reporter.Err(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Stats returns the buffer state of all asynchronous handlers.
+func (m *MultiHandler) Stats() []HandlerStats {
+	var stats []HandlerStats
+	for _, h := range m.handlers {
+		if source, ok := h.(interface{ Stats() HandlerStats }); ok {
+			stats = append(stats, source.Stats())
+		}
+	}
+	return stats
+}
+
+// Flush flushes all handlers concurrently and returns their combined errors.
+func (m *MultiHandler) Flush(ctx context.Context) error {
+	return m.each(func(h slog.Handler) error {
+		if flusher, ok := h.(interface{ Flush(context.Context) error }); ok {
+			return flusher.Flush(ctx)
+		}
+		return nil
+	})
+}
+
+// CloseAll drains and closes all handlers concurrently, giving them until ctx is done
+// to deliver buffered records, and returns their combined errors.
+func (m *MultiHandler) CloseAll(ctx context.Context) error {
+	return m.each(func(h slog.Handler) error {
+		switch closer := h.(type) {
+		case interface{ Shutdown(context.Context) error }:
+			return closer.Shutdown(ctx)
+		case interface{ Close() error }:
+			return closer.Close()
+		}
+		return nil
+	})
+}
+
+// each calls fn for every handler concurrently and joins the errors.
+func (m *MultiHandler) each(fn func(h slog.Handler) error) error {
+	errs := make([]error, len(m.handlers))
+	var wg sync.WaitGroup
+	for i, h := range m.handlers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(h)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// MultiHandlerFrom returns the MultiHandler behind the logger, unwrapping middleware handlers.
+func MultiHandlerFrom(logger *slog.Logger) (*MultiHandler, bool) {
+	handler := logger.Handler()
+	for {
+		switch h := handler.(type) {
+		case *MultiHandler:
+			return h, true
+		case interface{ Unwrap() slog.Handler }:
+			handler = h.Unwrap()
+		default:
+			return nil, false
 		}
 	}
 }
 
+// Options configures the handlers created by NewLogger.
+type Options struct {
+	Brokers     []string     // Kafka brokers
+	KafkaTopic  string       // Kafka topic for log records
+	ServiceName string       // Service name, used for the log file directory and the Kafka message key and headers
+	BufferSize  int          // Buffer size of each asynchronous handler
+	Kafka       KafkaOptions // Kafka handler options
+	File        FileOptions  // Log file location, rotation and retention
+	KafkaLevel  slog.Level   // Minimum level sent to Kafka
+	FileLevel   slog.Level   // Minimum level written to the log file
+	StdoutLevel slog.Level   // Minimum level written to stdout
+}
+
 // NewLogger initializes the combined logger with Kafka, File, and Stdout handlers.
-func NewLogger(brokers []string, kafkaTopic, serviceName string, bufferSize int) (*slog.Logger, error) {
-	kafkaHandler, err := NewKafkaHandler(brokers, kafkaTopic, bufferSize)
+// Kafka does not have to be reachable: until the Kafka handler connects in the background,
+// records are written to the file and stdout, and MultiHandler.Err reports the Kafka state.
+func NewLogger(opts Options) (*slog.Logger, error) {
+	if opts.Kafka.ServiceName == "" {
+		opts.Kafka.ServiceName = opts.ServiceName
+	}
+	kafkaHandler, err := StartKafkaHandler(opts.Brokers, opts.KafkaTopic, opts.BufferSize, opts.Kafka)
 	if err != nil {
 		return nil, err
 	}
+	kafkaHandler.SetLevel(opts.KafkaLevel)
 
-	fileHandler, err := NewFileHandler(serviceName, bufferSize)
+	fileHandler, err := NewFileHandler(opts.ServiceName, opts.BufferSize, opts.File)
 	if err != nil {
 		kafkaHandler.Close()
 		return nil, err
 	}
+	fileHandler.SetLevel(opts.FileLevel)
 
 	stdoutHandler := NewStdoutHandler()
+	stdoutHandler.SetLevel(opts.StdoutLevel)
 
 	multiHandler := NewMultiHandler(kafkaHandler, fileHandler, stdoutHandler)
 
-	logger := slog.New(multiHandler)
+	logger := slog.New(NewContextHandler(multiHandler))
 
 	return logger, nil
 }
+
+// NewCommandLogger creates a logger for maintenance commands: records go to the log file and stderr,
+// so commands work without Kafka and their stdout stays machine-readable.
+func NewCommandLogger(serviceName string, bufferSize int, fileOpts FileOptions) (*slog.Logger, error) {
+	fileHandler, err := NewFileHandler(serviceName, bufferSize, fileOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	multiHandler := NewMultiHandler(fileHandler, NewStderrHandler())
+
+	return slog.New(NewContextHandler(multiHandler)), nil
+}
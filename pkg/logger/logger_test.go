@@ -0,0 +1,248 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+)
+
+const testTimeout = 5 * time.Second
+
+func TestKafkaHandler(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		payload []byte
+		handler *KafkaHandler
+	)
+
+	slogtest.Run(t, func(t *testing.T) slog.Handler {
+		var err error
+		handler, err = newKafkaHandler("logs", 16, KafkaOptions{ServiceName: "test"})
+		if err != nil {
+			t.Fatalf("newKafkaHandler() error = %v", err)
+		}
+		producer := mocks.NewAsyncProducer(t, handler.saramaCfg)
+		producer.ExpectInputWithMessageCheckerFunctionAndSucceed(func(message *sarama.ProducerMessage) error {
+			value, err := message.Value.Encode()
+			mu.Lock()
+			payload = value
+			mu.Unlock()
+			return err
+		})
+		handler.attach(producer)
+		t.Cleanup(func() { handler.Close() })
+		return handler
+	}, func(t *testing.T) map[string]any {
+		flush(t, handler)
+		mu.Lock()
+		defer mu.Unlock()
+		var entry map[string]any
+		if err := json.Unmarshal(payload, &entry); err != nil {
+			t.Fatalf("failed to decode kafka message %q: %v", payload, err)
+		}
+		return entry
+	})
+}
+
+func TestFileHandler(t *testing.T) {
+	var (
+		handler *FileHandler
+		path    string
+	)
+
+	slogtest.Run(t, func(t *testing.T) slog.Handler {
+		dir := t.TempDir()
+		var err error
+		handler, err = NewFileHandler("test", 16, FileOptions{Dir: dir})
+		if err != nil {
+			t.Fatalf("NewFileHandler() error = %v", err)
+		}
+		path = filepath.Join(dir, "test", "app.log")
+		t.Cleanup(func() { handler.Close() })
+		return handler
+	}, func(t *testing.T) map[string]any {
+		flush(t, handler)
+		return parseLastLine(t, path)
+	})
+}
+
+func TestStdoutHandler(t *testing.T) {
+	var path string
+
+	slogtest.Run(t, func(t *testing.T) slog.Handler {
+		file, err := os.CreateTemp(t.TempDir(), "stdout")
+		if err != nil {
+			t.Fatalf("failed to create output file: %v", err)
+		}
+		t.Cleanup(func() { file.Close() })
+		path = file.Name()
+
+		handler := NewStdoutHandler()
+		handler.writer = file
+		return handler
+	}, func(t *testing.T) map[string]any {
+		return parseLastLine(t, path)
+	})
+}
+
+// flush waits until the records of an asynchronous handler are written.
+func flush(t *testing.T, handler interface{ Flush(context.Context) error }) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	if err := handler.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+}
+
+// parseLastLine reads the last line written to path and parses it with parseLine.
+func parseLastLine(t *testing.T, path string) map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	entry, err := parseLine(lines[len(lines)-1])
+	if err != nil {
+		t.Fatalf("failed to parse line %q: %v", lines[len(lines)-1], err)
+	}
+	return entry
+}
+
+// parseLine parses a line written by formatLine into a map in which qualified keys
+// such as G.a become nested maps, as slogtest expects.
+func parseLine(line string) (map[string]any, error) {
+	entry := make(map[string]any)
+	parts := strings.SplitN(line, " - ", 3)
+	if len(parts) < 2 {
+		return nil, strconv.ErrSyntax
+	}
+	entry[slog.LevelKey] = parts[0]
+	rest := parts[len(parts)-1]
+	if len(parts) == 3 {
+		entry[slog.TimeKey] = parts[1]
+	}
+
+	message, rest, _ := strings.Cut(rest, " ")
+	entry[slog.MessageKey] = message
+
+	for rest != "" {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			return nil, strconv.ErrSyntax
+		}
+		if strings.HasPrefix(value, `"`) {
+			quoted, err := strconv.QuotedPrefix(value)
+			if err != nil {
+				return nil, err
+			}
+			rest = strings.TrimPrefix(value[len(quoted):], " ")
+			if value, err = strconv.Unquote(quoted); err != nil {
+				return nil, err
+			}
+		} else {
+			value, rest, _ = strings.Cut(value, " ")
+		}
+
+		group := entry
+		names := strings.Split(key, ".")
+		for _, name := range names[:len(names)-1] {
+			nested, ok := group[name].(map[string]any)
+			if !ok {
+				nested = make(map[string]any)
+				group[name] = nested
+			}
+			group = nested
+		}
+		group[names[len(names)-1]] = value
+	}
+	return entry, nil
+}
+
+func TestShutdownIsIdempotent(t *testing.T) {
+	fileHandler, err := NewFileHandler("test", 16, FileOptions{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewFileHandler() error = %v", err)
+	}
+	kafkaHandler, err := newKafkaHandler("logs", 16, KafkaOptions{})
+	if err != nil {
+		t.Fatalf("newKafkaHandler() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	for _, handler := range []interface{ Shutdown(context.Context) error }{fileHandler, kafkaHandler} {
+		for i := 0; i < 2; i++ {
+			if err := handler.Shutdown(ctx); err != nil {
+				t.Errorf("%T.Shutdown() call %d error = %v", handler, i+1, err)
+			}
+		}
+	}
+
+	// A producer connected in the background after shutdown is not attached
+	producer := mocks.NewAsyncProducer(t, kafkaHandler.saramaCfg)
+	defer producer.Close()
+	if kafkaHandler.attach(producer) {
+		t.Errorf("attach() after Shutdown = true, want false")
+	}
+}
+
+func TestContextHandler(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "from-context")
+	tests := []struct {
+		name string
+		log  func(l *slog.Logger)
+		want string
+	}{
+		{
+			name: "attrs from WithAttrs win",
+			log:  func(l *slog.Logger) { l.With("request_id", "explicit").InfoContext(ctx, "msg") },
+			want: `{"level":"INFO","msg":"msg","request_id":"explicit"}`,
+		},
+		{
+			name: "context attrs stay at the top level",
+			log:  func(l *slog.Logger) { l.With("a", "b").WithGroup("G").With("c", "d").InfoContext(ctx, "msg", "e", "f") },
+			want: `{"level":"INFO","msg":"msg","a":"b","request_id":"from-context","G":{"c":"d","e":"f"}}`,
+		},
+		{
+			name: "record attrs in a group do not hide context attrs",
+			log:  func(l *slog.Logger) { l.WithGroup("G").InfoContext(ctx, "msg", "request_id", "nested") },
+			want: `{"level":"INFO","msg":"msg","request_id":"from-context","G":{"request_id":"nested"}}`,
+		},
+		{
+			name: "nested groups keep their attrs",
+			log:  func(l *slog.Logger) { l.WithGroup("G").With("a", "b").WithGroup("H").InfoContext(ctx, "msg", "c", "d") },
+			want: `{"level":"INFO","msg":"msg","request_id":"from-context","G":{"a":"b","H":{"c":"d"}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(NewContextHandler(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+				ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+					if len(groups) == 0 && attr.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return attr
+				},
+			}))))
+			if got := strings.TrimSpace(buf.String()); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package logger
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// ProducerOptions configures the Kafka producer of a KafkaHandler.
+// The zero value keeps the previous behaviour: acks from all replicas, 5 retries, no compression.
+type ProducerOptions struct {
+	RequiredAcks  string        // "all", "leader" or "none". Defaults to "all"
+	Retries       int           // Delivery attempts after the first one. Defaults to 5; negative disables retries
+	Compression   string        // "none", "gzip", "snappy", "lz4" or "zstd". Defaults to "none"
+	Linger        time.Duration // How long the producer waits to fill a batch (0 sends as soon as possible)
+	BatchBytes    int           // Batch size in bytes that triggers sending (0 uses the sarama default)
+	BatchMessages int           // Number of messages that triggers sending (0 uses the sarama default)
+	TLS           TLSOptions    // TLS connection to the brokers
+	SASL          SASLOptions   // SASL authentication
+}
+
+// TLSOptions configures TLS between the producer and the brokers.
+type TLSOptions struct {
+	Enabled  bool
+	CAFile   string // CA certificates in PEM to verify the brokers (empty uses the system pool)
+	CertFile string // Client certificate in PEM for mutual TLS
+	KeyFile  string // Client private key in PEM
+}
+
+// SASLOptions configures SASL authentication of the producer.
+type SASLOptions struct {
+	Mechanism string // "PLAIN", "SCRAM-SHA-256" or "SCRAM-SHA-512" (empty disables SASL)
+	Username  string
+	Password  string
+}
+
+// TopicRoute sends records at MinLevel and above to Topic.
+type TopicRoute struct {
+	MinLevel slog.Level
+	Topic    string
+}
+
+// newSaramaConfig builds the producer configuration from opts.
+func newSaramaConfig(opts ProducerOptions) (*sarama.Config, error) {
+	config := sarama.NewConfig()
+	config.Producer.Return.Successes = true
+	config.Producer.Return.Errors = true
+	config.Producer.Partitioner = sarama.NewHashPartitioner
+
+	switch opts.RequiredAcks {
+	case "", "all":
+		config.Producer.RequiredAcks = sarama.WaitForAll
+	case "leader":
+		config.Producer.RequiredAcks = sarama.WaitForLocal
+	case "none":
+		config.Producer.RequiredAcks = sarama.NoResponse
+	default:
+		return nil, fmt.Errorf("unknown kafka acks %q", opts.RequiredAcks)
+	}
+
+	switch {
+	case opts.Retries == 0:
+		config.Producer.Retry.Max = 5
+	case opts.Retries < 0:
+		config.Producer.Retry.Max = 0
+	default:
+		config.Producer.Retry.Max = opts.Retries
+	}
+
+	switch opts.Compression {
+	case "", "none":
+		config.Producer.Compression = sarama.CompressionNone
+	case "gzip":
+		config.Producer.Compression = sarama.CompressionGZIP
+	case "snappy":
+		config.Producer.Compression = sarama.CompressionSnappy
+	case "lz4":
+		config.Producer.Compression = sarama.CompressionLZ4
+	case "zstd":
+		config.Producer.Compression = sarama.CompressionZSTD
+	default:
+		return nil, fmt.Errorf("unknown kafka compression codec %q", opts.Compression)
+	}
+
+	config.Producer.Flush.Frequency = opts.Linger
+	config.Producer.Flush.Bytes = opts.BatchBytes
+	config.Producer.Flush.Messages = opts.BatchMessages
+
+	if opts.TLS.Enabled {
+		tlsConfig, err := newTLSConfig(opts.TLS)
+		if err != nil {
+			return nil, err
+		}
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+	}
+
+	if opts.SASL.Mechanism != "" {
+		config.Net.SASL.Enable = true
+		config.Net.SASL.User = opts.SASL.Username
+		config.Net.SASL.Password = opts.SASL.Password
+		switch opts.SASL.Mechanism {
+		case sarama.SASLTypePlaintext:
+			config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		case sarama.SASLTypeSCRAMSHA256:
+			config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+			config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &scramClient{hashGenerator: scram.SHA256}
+			}
+		case sarama.SASLTypeSCRAMSHA512:
+			config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+			config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &scramClient{hashGenerator: scram.SHA512}
+			}
+		default:
+			return nil, fmt.Errorf("unknown kafka SASL mechanism %q", opts.SASL.Mechanism)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid kafka producer configuration: %w", err)
+	}
+	return config, nil
+}
+
+// newTLSConfig loads the CA and the client certificate for the connection to the brokers.
+func newTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if opts.CAFile != "" {
+		data, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kafka CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, errors.New("kafka CA file contains no certificates")
+		}
+		config.RootCAs = pool
+	}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kafka client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// scramClient implements sarama.SCRAMClient for the SCRAM-SHA-256 and SCRAM-SHA-512 mechanisms.
+type scramClient struct {
+	hashGenerator scram.HashGeneratorFcn
+	conversation  *scram.ClientConversation
+}
+
+// Begin starts a SCRAM conversation for the user.
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.hashGenerator.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.conversation = client.NewConversation()
+	return nil
+}
+
+// Step answers a server challenge.
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.conversation.Step(challenge)
+}
+
+// Done reports whether the conversation is complete.
+func (c *scramClient) Done() bool {
+	return c.conversation.Done()
+}
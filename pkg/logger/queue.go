@@ -0,0 +1,227 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy is what an asynchronous handler does with a record when its buffer is full.
+type OverflowPolicy string
+
+const (
+	DropNewest       OverflowPolicy = "drop_newest"   // Reject the new record
+	DropOldest       OverflowPolicy = "drop_oldest"   // Evict the oldest buffered record to make room
+	BlockWithTimeout OverflowPolicy = "block_timeout" // Wait up to BlockTimeout for room, then reject the record
+	Block            OverflowPolicy = "block"         // Wait for room; callers stall while the sink is slow or unreachable
+)
+
+// QueueOptions configures how an asynchronous handler buffers records.
+type QueueOptions struct {
+	Overflow     OverflowPolicy  // Policy for a full buffer. Defaults to DropNewest
+	BlockTimeout time.Duration   // How long BlockWithTimeout waits for room
+	Sampling     SamplingOptions // Per-level sampling of identical messages
+	DedupWindow  time.Duration   // Identical consecutive records within this window are collapsed into a summary (0 disables)
+}
+
+// SamplingOptions configures sampling of identical messages, counted per level and message over each tick.
+type SamplingOptions struct {
+	Tick  time.Duration               // Period over which identical messages are counted. Defaults to 1s
+	Rules map[slog.Level]SamplingRule // Levels without a rule are not sampled
+}
+
+// SamplingRule keeps the first Initial identical messages of a tick and then every Thereafter-th one.
+// With Thereafter 0 the rest of the tick is dropped.
+type SamplingRule struct {
+	Initial    int
+	Thereafter int
+}
+
+// offer puts item into ch according to the overflow policy and returns the records that
+// did not fit: item itself, or the oldest buffered records evicted by DropOldest.
+func offer[T any](ch chan T, item T, opts QueueOptions, quit <-chan struct{}) []T {
+	select {
+	case ch <- item:
+		return nil
+	default:
+	}
+
+	switch opts.Overflow {
+	case DropOldest:
+		var evicted []T
+		for i := 0; i <= cap(ch); i++ {
+			select {
+			case old := <-ch:
+				evicted = append(evicted, old)
+			default:
+			}
+			select {
+			case ch <- item:
+				return evicted
+			default:
+			}
+		}
+		return append(evicted, item)
+	case BlockWithTimeout:
+		timer := time.NewTimer(opts.BlockTimeout)
+		defer timer.Stop()
+		select {
+		case ch <- item:
+			return nil
+		case <-timer.C:
+		case <-quit:
+		}
+		return []T{item}
+	case Block:
+		select {
+		case ch <- item:
+			return nil
+		case <-quit:
+		}
+		return []T{item}
+	default:
+		return []T{item}
+	}
+}
+
+// filteredRecord is a record that passed sampling and deduplication, with its resolved attributes.
+type filteredRecord struct {
+	record slog.Record
+	attrs  []slog.Attr
+}
+
+// sampleKey identifies identical messages for sampling.
+type sampleKey struct {
+	level   slog.Level
+	message string
+}
+
+// dedupEntry is the last record let through by deduplication and the number of its repeats since.
+type dedupEntry struct {
+	key      string
+	first    time.Time
+	last     filteredRecord
+	repeated int
+}
+
+// recordFilter samples and deduplicates the records of a handler before they are buffered.
+type recordFilter struct {
+	opts QueueOptions
+
+	mu        sync.Mutex
+	tickStart time.Time
+	counts    map[sampleKey]int
+	dedup     *dedupEntry
+
+	sampled      atomic.Uint64
+	deduplicated atomic.Uint64
+}
+
+// newRecordFilter returns a filter for opts, or nil if sampling and deduplication are disabled.
+func newRecordFilter(opts QueueOptions) *recordFilter {
+	if len(opts.Sampling.Rules) == 0 && opts.DedupWindow <= 0 {
+		return nil
+	}
+	if opts.Sampling.Tick <= 0 {
+		opts.Sampling.Tick = time.Second
+	}
+	return &recordFilter{opts: opts, counts: make(map[sampleKey]int)}
+}
+
+// apply returns the records to buffer for record: none if it is sampled out or repeats
+// the previous record, otherwise the record itself, preceded by the summary of the repeats
+// of the previous record if there were any.
+func (f *recordFilter) apply(record slog.Record, attrs []slog.Attr) []filteredRecord {
+	current := filteredRecord{record: record, attrs: attrs}
+	if f == nil {
+		return []filteredRecord{current}
+	}
+
+	now := record.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.sample(record, now) {
+		f.sampled.Add(1)
+		return nil
+	}
+	if f.opts.DedupWindow <= 0 {
+		return []filteredRecord{current}
+	}
+
+	key := string(formatLine(record.Level.String(), slog.Record{Message: record.Message}, "", attrs))
+	if f.dedup != nil && f.dedup.key == key && now.Sub(f.dedup.first) < f.opts.DedupWindow {
+		f.dedup.repeated++
+		f.dedup.last = current
+		f.deduplicated.Add(1)
+		return nil
+	}
+
+	var out []filteredRecord
+	if summary, ok := f.summaryLocked(); ok {
+		out = append(out, summary)
+	}
+	f.dedup = &dedupEntry{key: key, first: now, last: current}
+	return append(out, current)
+}
+
+// sample reports whether the record is kept by the sampling rule of its level.
+func (f *recordFilter) sample(record slog.Record, now time.Time) bool {
+	rule, ok := f.opts.Sampling.Rules[record.Level]
+	if !ok {
+		return true
+	}
+
+	if now.Sub(f.tickStart) >= f.opts.Sampling.Tick || now.Before(f.tickStart) {
+		f.tickStart = now
+		clear(f.counts)
+	}
+	key := sampleKey{level: record.Level, message: record.Message}
+	f.counts[key]++
+	n := f.counts[key]
+	return n <= rule.Initial || (rule.Thereafter > 0 && (n-rule.Initial)%rule.Thereafter == 0)
+}
+
+// pending returns the summary of the repeats that have not been reported yet, so that
+// they are not lost when the handler is flushed or closed.
+func (f *recordFilter) pending() []filteredRecord {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	summary, ok := f.summaryLocked()
+	if !ok {
+		return nil
+	}
+	f.dedup = nil
+	return []filteredRecord{summary}
+}
+
+// summaryLocked returns a record "<message> (repeated N times)" for the repeats of the last record.
+func (f *recordFilter) summaryLocked() (filteredRecord, bool) {
+	if f.dedup == nil || f.dedup.repeated == 0 {
+		return filteredRecord{}, false
+	}
+	last := f.dedup.last
+	record := slog.NewRecord(last.record.Time, last.record.Level,
+		fmt.Sprintf("%s (repeated %d times)", last.record.Message, f.dedup.repeated), last.record.PC)
+	attrs := append(append([]slog.Attr(nil), last.attrs...), slog.Int("repeated", f.dedup.repeated))
+	f.dedup.repeated = 0
+	return filteredRecord{record: record, attrs: attrs}, true
+}
+
+// stats returns the number of sampled out and deduplicated records.
+func (f *recordFilter) stats() (sampled, deduplicated uint64) {
+	if f == nil {
+		return 0, 0
+	}
+	return f.sampled.Load(), f.deduplicated.Load()
+}
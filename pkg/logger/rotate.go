@@ -0,0 +1,301 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp in the names of rotated files, e.g. app-2024-05-01T12-00-00.000.log.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// FileOptions configures the log file of a FileHandler.
+type FileOptions struct {
+	Dir            string        // Log directory; the file is <Dir>/<service>/app.log. Defaults to "logs"
+	MaxSize        int64         // Rotate when the file would exceed this many bytes (0 disables size rotation)
+	RotateInterval time.Duration // Rotate at multiples of this interval, e.g. 24h rotates at midnight UTC (0 disables)
+	MaxBackups     int           // Number of rotated files to keep (0 keeps all)
+	MaxAge         time.Duration // Delete rotated files older than this (0 keeps all)
+	Compress       bool          // Gzip rotated files
+	Queue          QueueOptions  // Buffer overflow policy, sampling and deduplication
+}
+
+// rotatingFile is a log file that rotates by size and time. Rotated files are compressed
+// and cleaned up in the background so that writes are not blocked.
+type rotatingFile struct {
+	path string
+	opts FileOptions
+
+	mu           sync.Mutex
+	file         *os.File
+	size         int64
+	nextRotation time.Time
+
+	millChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// openRotatingFile creates the directory and opens the log file for appending.
+func openRotatingFile(path string, opts FileOptions) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	r := &rotatingFile{
+		path:     path,
+		opts:     opts,
+		millChan: make(chan struct{}, 1),
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	r.wg.Add(1)
+	go r.mill()
+	r.startMill()
+	return r, nil
+}
+
+// open opens the file at r.path and schedules the next time-based rotation.
+// A file left from an earlier interval is rotated on the first write.
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	r.file = file
+	r.size = info.Size()
+	if r.opts.RotateInterval > 0 {
+		start := time.Now()
+		if r.size > 0 {
+			start = info.ModTime()
+		}
+		r.nextRotation = start.UTC().Truncate(r.opts.RotateInterval).Add(r.opts.RotateInterval)
+	}
+	return nil
+}
+
+// Write writes p to the file, rotating it first if p does not fit or the rotation time has come.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size > 0 && r.dueForRotation(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to rotate log file %s: %v\n", r.path, err)
+		}
+	}
+	if r.file == nil {
+		return 0, fmt.Errorf("log file %s is not open", r.path)
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// dueForRotation reports whether the file must be rotated before writing n more bytes.
+func (r *rotatingFile) dueForRotation(n int64) bool {
+	if r.opts.MaxSize > 0 && r.size+n > r.opts.MaxSize {
+		return true
+	}
+	return r.opts.RotateInterval > 0 && !time.Now().Before(r.nextRotation)
+}
+
+// rotate renames the current file to a timestamped backup and opens a new one.
+func (r *rotatingFile) rotate() error {
+	if r.file != nil {
+		if err := r.file.Close(); err != nil {
+			return err
+		}
+		r.file = nil
+	}
+
+	ext := filepath.Ext(r.path)
+	backup := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(r.path, ext), time.Now().UTC().Format(backupTimeFormat), ext)
+	if err := os.Rename(r.path, backup); err != nil && !os.IsNotExist(err) {
+		// Keep writing to the old file rather than losing records
+		if openErr := r.open(); openErr != nil {
+			return fmt.Errorf("%w; reopen failed: %v", err, openErr)
+		}
+		return err
+	}
+
+	if err := r.open(); err != nil {
+		return err
+	}
+	r.startMill()
+	return nil
+}
+
+// Reopen closes and reopens the file at the same path, for use after an external tool
+// such as logrotate has moved it away.
+func (r *rotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	return r.open()
+}
+
+// Sync commits the written records to stable storage.
+func (r *rotatingFile) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Sync()
+}
+
+// Close closes the file and waits for compression and cleanup of rotated files to finish.
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.mu.Unlock()
+
+	close(r.millChan)
+	r.wg.Wait()
+	return err
+}
+
+// startMill requests compression and cleanup of rotated files without waiting for it.
+func (r *rotatingFile) startMill() {
+	select {
+	case r.millChan <- struct{}{}:
+	default:
+	}
+}
+
+// mill compresses and deletes rotated files on request until the file is closed.
+func (r *rotatingFile) mill() {
+	defer r.wg.Done()
+	for range r.millChan {
+		if err := r.millOnce(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to clean up rotated log files of %s: %v\n", r.path, err)
+		}
+	}
+}
+
+// backupFile is a rotated log file.
+type backupFile struct {
+	path      string
+	rotatedAt time.Time
+}
+
+// millOnce gzips uncompressed backups and deletes the ones beyond MaxBackups or older than MaxAge.
+func (r *rotatingFile) millOnce() error {
+	backups, err := r.backups()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	var keep []backupFile
+	for i, backup := range backups {
+		expired := r.opts.MaxAge > 0 && time.Since(backup.rotatedAt) > r.opts.MaxAge
+		if (r.opts.MaxBackups > 0 && i >= r.opts.MaxBackups) || expired {
+			if err := os.Remove(backup.path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		keep = append(keep, backup)
+	}
+
+	if r.opts.Compress {
+		for _, backup := range keep {
+			if strings.HasSuffix(backup.path, ".gz") {
+				continue
+			}
+			if err := compressFile(backup.path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// backups returns the rotated files of r, newest first.
+func (r *rotatingFile) backups() ([]backupFile, error) {
+	dir := filepath.Dir(r.path)
+	ext := filepath.Ext(r.path)
+	prefix := strings.TrimSuffix(filepath.Base(r.path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backupFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		rotatedAt, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), rotatedAt: rotatedAt})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].rotatedAt.After(backups[j].rotatedAt) })
+	return backups, nil
+}
+
+// compressFile gzips path into path.gz and removes the original.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	src.Close()
+	return os.Remove(path)
+}
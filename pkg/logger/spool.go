@@ -0,0 +1,394 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+const (
+	// spoolSegmentSize is the size after which the spool starts a new segment file.
+	// Segments are replayed and deleted as a whole.
+	spoolSegmentSize = 1 << 20
+
+	// spoolSegmentPrefix and spoolSegmentExt form segment names such as segment-00000000000000000001.jsonl.
+	spoolSegmentPrefix = "segment-"
+	spoolSegmentExt    = ".jsonl"
+)
+
+var (
+	// errSpoolFull is returned when a record does not fit into the spool size limit.
+	errSpoolFull = errors.New("kafka spool is full")
+	// errSpoolClosed is returned when a record is appended after the spool is closed,
+	// e.g. by a producer result that arrives after Shutdown stopped waiting for it.
+	errSpoolClosed = errors.New("kafka spool is closed")
+)
+
+// SpoolOptions configures the disk spool of a KafkaHandler.
+type SpoolOptions struct {
+	Dir            string        // Spool directory; empty disables the spool
+	MaxSize        int64         // Maximum total size of spooled records in bytes; records beyond it are dropped
+	ReplayInterval time.Duration // How often spooled records are replayed while Kafka is reachable. Defaults to 1s
+}
+
+// spooledHeader is a Kafka record header stored in the spool.
+type spooledHeader struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// spooledMessage is a Kafka message stored in the spool as one JSON line.
+type spooledMessage struct {
+	Topic   string          `json:"topic"`
+	Key     []byte          `json:"key,omitempty"`
+	Value   []byte          `json:"value"`
+	Headers []spooledHeader `json:"headers,omitempty"`
+}
+
+// diskSpool stores Kafka messages that could not be delivered in numbered segment files,
+// so that they survive restarts and can be replayed in the order they were spooled.
+type diskSpool struct {
+	dir     string
+	maxSize int64
+
+	mu          sync.Mutex
+	writer      *os.File
+	writerSeq   uint64
+	writerSize  int64
+	nextSeq     uint64
+	segmentSize map[uint64]int64
+	totalSize   int64
+	closed      bool
+}
+
+// openSpool creates the spool directory and picks up segments left by a previous run.
+func openSpool(opts SpoolOptions) (*diskSpool, error) {
+	if err := os.MkdirAll(opts.Dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create kafka spool directory: %w", err)
+	}
+
+	s := &diskSpool{
+		dir:         opts.Dir,
+		maxSize:     opts.MaxSize,
+		nextSeq:     1,
+		segmentSize: make(map[uint64]int64),
+	}
+
+	seqs, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	for _, seq := range seqs {
+		info, err := os.Stat(s.segmentPath(seq))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kafka spool: %w", err)
+		}
+		s.segmentSize[seq] = info.Size()
+		s.totalSize += info.Size()
+		s.nextSeq = seq + 1
+	}
+	return s, nil
+}
+
+// segments returns the sequence numbers of the segment files, oldest first.
+func (s *diskSpool) segments() ([]uint64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kafka spool: %w", err)
+	}
+
+	var seqs []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, spoolSegmentPrefix) || !strings.HasSuffix(name, spoolSegmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, spoolSegmentPrefix), spoolSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs, nil
+}
+
+// segmentPath returns the path of the segment file with the sequence number seq.
+func (s *diskSpool) segmentPath(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s%020d%s", spoolSegmentPrefix, seq, spoolSegmentExt))
+}
+
+// Append stores the message at the end of the spool.
+func (s *diskSpool) Append(message *sarama.ProducerMessage) error {
+	line, err := encodeSpooledMessage(message)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errSpoolClosed
+	}
+	if s.maxSize > 0 && s.totalSize+int64(len(line)) > s.maxSize {
+		return errSpoolFull
+	}
+
+	if s.writer == nil || s.writerSize >= spoolSegmentSize {
+		if err := s.sealLocked(); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(s.segmentPath(s.nextSeq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to create kafka spool segment: %w", err)
+		}
+		s.writer, s.writerSeq, s.writerSize = file, s.nextSeq, 0
+		s.nextSeq++
+	}
+
+	n, err := s.writer.Write(line)
+	s.writerSize += int64(n)
+	s.segmentSize[s.writerSeq] += int64(n)
+	s.totalSize += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write kafka spool segment: %w", err)
+	}
+	return nil
+}
+
+// sealLocked syncs and closes the segment being written, so that it can be replayed.
+func (s *diskSpool) sealLocked() error {
+	if s.writer == nil {
+		return nil
+	}
+	syncErr := s.writer.Sync()
+	closeErr := s.writer.Close()
+	s.writer = nil
+	if err := errors.Join(syncErr, closeErr); err != nil {
+		return fmt.Errorf("failed to close kafka spool segment: %w", err)
+	}
+	return nil
+}
+
+// Oldest returns the sequence number and messages of the oldest segment.
+// If that segment is still being written, it is sealed first.
+func (s *diskSpool) Oldest() (uint64, []*sarama.ProducerMessage, error) {
+	s.mu.Lock()
+	seqs, err := s.segments()
+	if err != nil || len(seqs) == 0 {
+		s.mu.Unlock()
+		return 0, nil, err
+	}
+	seq := seqs[0]
+	if s.writer != nil && s.writerSeq == seq {
+		if err := s.sealLocked(); err != nil {
+			s.mu.Unlock()
+			return 0, nil, err
+		}
+	}
+	s.mu.Unlock()
+
+	file, err := os.Open(s.segmentPath(seq))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to open kafka spool segment: %w", err)
+	}
+	defer file.Close()
+
+	var messages []*sarama.ProducerMessage
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), spoolSegmentSize)
+	for scanner.Scan() {
+		message, err := decodeSpooledMessage(scanner.Bytes())
+		if err != nil {
+			// A record cut short by a crash is skipped rather than blocking the whole spool
+			fmt.Fprintf(os.Stderr, "skipping corrupted kafka spool record in segment %d: %v\n", seq, err)
+			continue
+		}
+		messages = append(messages, message)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, nil, fmt.Errorf("failed to read kafka spool segment: %w", err)
+	}
+	return seq, messages, nil
+}
+
+// Remove deletes a replayed segment.
+func (s *diskSpool) Remove(seq uint64) error {
+	if err := os.Remove(s.segmentPath(seq)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove kafka spool segment: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.totalSize -= s.segmentSize[seq]
+	delete(s.segmentSize, seq)
+	return nil
+}
+
+// Size returns the total size of spooled records in bytes.
+func (s *diskSpool) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.totalSize
+}
+
+// Close seals the segment being written. Later appends fail with errSpoolClosed,
+// so no segment is left unsealed.
+func (s *diskSpool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return s.sealLocked()
+}
+
+// encodeSpooledMessage encodes a producer message as a JSON line.
+func encodeSpooledMessage(message *sarama.ProducerMessage) ([]byte, error) {
+	spooled := spooledMessage{Topic: message.Topic}
+	var err error
+	if message.Key != nil {
+		if spooled.Key, err = message.Key.Encode(); err != nil {
+			return nil, fmt.Errorf("failed to encode kafka message key: %w", err)
+		}
+	}
+	if message.Value != nil {
+		if spooled.Value, err = message.Value.Encode(); err != nil {
+			return nil, fmt.Errorf("failed to encode kafka message value: %w", err)
+		}
+	}
+	for _, header := range message.Headers {
+		spooled.Headers = append(spooled.Headers, spooledHeader{Key: header.Key, Value: header.Value})
+	}
+
+	line, err := json.Marshal(spooled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode kafka spool record: %w", err)
+	}
+	return append(line, '\n'), nil
+}
+
+// decodeSpooledMessage decodes a JSON line written by encodeSpooledMessage.
+func decodeSpooledMessage(line []byte) (*sarama.ProducerMessage, error) {
+	var spooled spooledMessage
+	if err := json.Unmarshal(line, &spooled); err != nil {
+		return nil, err
+	}
+
+	message := &sarama.ProducerMessage{
+		Topic: spooled.Topic,
+		Value: sarama.ByteEncoder(spooled.Value),
+	}
+	if spooled.Key != nil {
+		message.Key = sarama.ByteEncoder(spooled.Key)
+	}
+	for _, header := range spooled.Headers {
+		message.Headers = append(message.Headers, sarama.RecordHeader{Key: header.Key, Value: header.Value})
+	}
+	return message, nil
+}
+
+// replayBatch tracks delivery of the messages of one replayed segment.
+type replayBatch struct {
+	results chan error
+}
+
+// replayLoop replays spooled messages while Kafka is reachable, until the handler is closed.
+func (k *KafkaHandler) replayLoop(interval time.Duration) {
+	defer k.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			k.replay()
+		case <-k.quitChan:
+			return
+		}
+	}
+}
+
+// replay sends spooled segments to Kafka oldest first. A segment is deleted only after
+// all its messages are delivered; otherwise replay stops and the segment is retried on the
+// next tick, so records are replayed in order and at least once. Replay depends only on
+// the producer being connected: a failed live delivery must not keep the spool from draining
+// on a service that logs too rarely for a successful delivery to clear it.
+func (k *KafkaHandler) replay() {
+	for k.isConnected() {
+		seq, messages, err := k.spool.Oldest()
+		if err != nil {
+			k.spoolError("failed to read kafka spool", err)
+			return
+		}
+		if messages == nil && seq == 0 {
+			return
+		}
+
+		batch := &replayBatch{results: make(chan error, len(messages))}
+		for _, message := range messages {
+			message.Metadata = batch
+			select {
+			case k.producer.Input() <- message:
+			case <-k.quitChan:
+				return
+			}
+		}
+
+		var failed error
+		for range messages {
+			select {
+			case err := <-batch.results:
+				if err != nil && failed == nil {
+					failed = err
+				}
+			case <-k.quitChan:
+				return
+			}
+		}
+		if failed != nil {
+			k.spoolError("failed to replay kafka spool, will retry", failed)
+			return
+		}
+
+		if err := k.spool.Remove(seq); err != nil {
+			k.spoolError("failed to remove replayed kafka spool segment", err)
+			return
+		}
+		k.replayed.Add(uint64(len(messages)))
+	}
+}
+
+// spoolMessage stores an undeliverable message in the spool, or counts it as dropped
+// if the spool is disabled or full.
+func (k *KafkaHandler) spoolMessage(message *sarama.ProducerMessage) {
+	if k.spool == nil {
+		k.dropped.Add(1)
+		return
+	}
+	if err := k.spool.Append(message); err != nil {
+		k.dropped.Add(1)
+		if !errors.Is(err, errSpoolFull) {
+			k.spoolError("failed to spool kafka message", err)
+		}
+		return
+	}
+	k.spooled.Add(1)
+}
+
+// spoolError counts a failed spool read, replay or write and reports it on stderr,
+// since the failure may be caused by the logger itself.
+func (k *KafkaHandler) spoolError(msg string, err error) {
+	k.spoolErrs.Add(1)
+	fmt.Fprintf(os.Stderr, "%s: %v\n", msg, err)
+}
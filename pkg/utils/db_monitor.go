@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DBMonitor периодически проверяет соединение с базой данных и логирует переходы между
+// доступностью и недоступностью, а также состояние пула. Статистика пула для метрик
+// снимается metrics.RegisterDBStats, готовность сервиса — healthcheck.Checker.
+type DBMonitor struct {
+	db       *sql.DB
+	interval time.Duration
+	logger   *slog.Logger
+
+	lastErr error // Результат последней проверки; используется только горутиной run
+
+	quitChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewDBMonitor создает новый экземпляр DBMonitor
+func NewDBMonitor(db *sql.DB, interval time.Duration, logger *slog.Logger) *DBMonitor {
+	return &DBMonitor{
+		db:       db,
+		interval: interval,
+		logger:   logger,
+		quitChan: make(chan struct{}),
+	}
+}
+
+// Start запускает фоновую проверку соединения
+func (m *DBMonitor) Start() {
+	if m.interval <= 0 {
+		return
+	}
+
+	m.wg.Add(1)
+	go m.run()
+}
+
+// run проверяет соединение с заданным интервалом до остановки монитора
+func (m *DBMonitor) run() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.check()
+		case <-m.quitChan:
+			return
+		}
+	}
+}
+
+// check выполняет одну проверку соединения и логирует состояние пула
+func (m *DBMonitor) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), m.interval)
+	defer cancel()
+
+	err := m.db.PingContext(ctx)
+
+	wasHealthy := m.lastErr == nil
+	m.lastErr = err
+
+	stats := m.db.Stats()
+	switch {
+	case err != nil && wasHealthy:
+		m.logger.Error("database became unreachable", slog.Any("error", err))
+	case err == nil && !wasHealthy:
+		m.logger.Info("database is reachable again")
+	}
+
+	m.logger.Debug("database pool stats",
+		slog.Int("open", stats.OpenConnections),
+		slog.Int("in_use", stats.InUse),
+		slog.Int("idle", stats.Idle),
+		slog.Int64("wait_count", stats.WaitCount),
+		slog.Duration("wait_duration", stats.WaitDuration),
+	)
+}
+
+// Stop останавливает фоновую проверку и дожидается её завершения
+func (m *DBMonitor) Stop() {
+	close(m.quitChan)
+	m.wg.Wait()
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/watchlist-kata/watchlist/internal/config"
+)
+
+// DatabaseDSN возвращает строку подключения к базе данных. DATABASE_URL_FILE и DATABASE_URL
+// заменяют параметры DB_* целиком; иначе строка собирается из DB_*, а пароль берется
+// из DB_PASSWORD_FILE или DB_PASSWORD. Файлы секретов читаются при каждом вызове.
+func DatabaseDSN(cfg *config.Config) (string, error) {
+	if cfg.DatabaseURLFile != "" {
+		url, err := readSecretFile(cfg.DatabaseURLFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read DATABASE_URL_FILE: %w", err)
+		}
+		return url, nil
+	}
+	if cfg.DatabaseURL != "" {
+		return cfg.DatabaseURL, nil
+	}
+
+	password := cfg.DBPassword
+	if cfg.DBPasswordFile != "" {
+		var err error
+		if password, err = readSecretFile(cfg.DBPasswordFile); err != nil {
+			return "", fmt.Errorf("failed to read DB_PASSWORD_FILE: %w", err)
+		}
+	}
+
+	params := []struct{ key, value string }{
+		{"host", cfg.DBHost},
+		{"port", cfg.DBPort},
+		{"user", cfg.DBUser},
+		{"password", password},
+		{"dbname", cfg.DBName},
+		{"sslmode", cfg.DBSSLMode},
+	}
+	pairs := make([]string, 0, len(params))
+	for _, p := range params {
+		pairs = append(pairs, p.key+"="+quoteDSNValue(p.value))
+	}
+	return strings.Join(pairs, " "), nil
+}
+
+// ParseDatabaseDSN собирает строку подключения и разбирает её, не раскрывая значение в ошибке
+func ParseDatabaseDSN(cfg *config.Config) (*pgx.ConnConfig, error) {
+	dsn, err := DatabaseDSN(cfg)
+	if err != nil {
+		return nil, err
+	}
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		// Текст ошибки pgx может содержать строку подключения вместе с паролем
+		return nil, fmt.Errorf("invalid database connection string")
+	}
+	return connConfig, nil
+}
+
+// quoteDSNValue заключает значение в одинарные кавычки по правилам libpq,
+// чтобы пробелы, кавычки и обратные слэши в пароле не ломали строку подключения
+func quoteDSNValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// readSecretFile читает значение секрета из файла, отбрасывая завершающий перевод строки
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// refreshCredentials перечитывает файлы секретов перед каждым новым соединением пула
+// и заменяет параметры соединения целиком, так что изменение хоста, порта или базы данных
+// в DATABASE_URL_FILE применяется так же, как смена пароля
+func refreshCredentials(cfg *config.Config) func(context.Context, *pgx.ConnConfig) error {
+	return func(_ context.Context, connConfig *pgx.ConnConfig) error {
+		if cfg.DBPasswordFile == "" && cfg.DatabaseURLFile == "" {
+			return nil
+		}
+		fresh, err := ParseDatabaseDSN(cfg)
+		if err != nil {
+			return err
+		}
+		*connConfig = *fresh
+		return nil
+	}
+}
@@ -1,20 +1,89 @@
 package utils
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
 	"github.com/watchlist-kata/watchlist/internal/config"
 )
 
-// ConnectToDatabase устанавливает подключение к базе данных PostgreSQL
-func ConnectToDatabase(cfg *config.Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
-		cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort, cfg.DBSSLMode)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// ConnectToDatabase устанавливает подключение к базе данных PostgreSQL.
+// Если база данных ещё не готова, подключение повторяется с экспоненциальной задержкой и джиттером
+// до cfg.DBConnectRetries раз или до отмены контекста.
+func ConnectToDatabase(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*gorm.DB, error) {
+	// Ошибка в строке подключения или недоступный файл секрета не исправятся повторными попытками
+	connConfig, err := ParseDatabaseDSN(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+		return nil, err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= cfg.DBConnectRetries; attempt++ {
+		if attempt > 0 {
+			delay := backoff(attempt, cfg.DBConnectBackoff, cfg.DBConnectMaxBackoff)
+			logger.WarnContext(ctx, fmt.Sprintf("database is not ready, retrying in %s (attempt %d of %d)", delay, attempt, cfg.DBConnectRetries), slog.Any("error", lastErr))
+
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("failed to connect to database: %w", ctx.Err())
+			case <-time.After(delay):
+			}
+		}
+
+		db, err := openDatabase(ctx, connConfig, cfg)
+		if err == nil {
+			return db, nil
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("failed to connect to database after %d attempts: %w", cfg.DBConnectRetries+1, lastErr)
+}
+
+// openDatabase открывает пул соединений, настраивает его и проверяет доступность базы данных.
+// Перед каждым новым соединением пула файлы секретов перечитываются.
+func openDatabase(ctx context.Context, connConfig *pgx.ConnConfig, cfg *config.Config) (*gorm.DB, error) {
+	sqlDB := stdlib.OpenDB(*connConfig, stdlib.OptionBeforeConnect(refreshCredentials(cfg)))
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	sqlDB.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		sqlDB.Close()
+		return nil, err
 	}
 	return db, nil
 }
+
+// backoff вычисляет задержку перед очередной попыткой: base*2^(attempt-1), не больше max,
+// со случайным джиттером в диапазоне [d/2, d)
+func backoff(attempt int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(d-half)
+}